@@ -2,10 +2,12 @@ module github.com/phdah/sql-tdg
 
 go 1.23.4
 
-require github.com/stretchr/testify v1.10.0
+require (
+	github.com/alecthomas/participle/v2 v2.1.4
+	github.com/stretchr/testify v1.10.0
+)
 
 require (
-	github.com/alecthomas/participle/v2 v2.1.4 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
@@ -0,0 +1,125 @@
+package interop
+
+import (
+	"fmt"
+	"maps"
+	"math/rand"
+	"slices"
+
+	"github.com/phdah/sql-tdg/internals/parser"
+	"github.com/phdah/sql-tdg/internals/solver"
+	"github.com/phdah/sql-tdg/internals/types"
+)
+
+// alternatives are the ways a comparison on a CASE expression, or an
+// OR, can hold, as one for each branch of the CASE that passes it. The
+// conditions of an alternative all hold together.
+type alternatives [][]boundCondition
+
+// bindCase binds the conditions of every branch of a comparison on a
+// CASE expression, which only compare columns with values, but may hold
+// CASEs and ORs of their own.
+func (s *scope) bindCase(c parser.ConditionsIR, b *bindings) error {
+	var alts alternatives
+	for _, alt := range c.Alternatives {
+		inner := bindings{pos: b.pos}
+		if err := s.bindTree(alt, &inner); err != nil {
+			return fmt.Errorf("CASE: %w", err)
+		}
+		combined, err := inner.combinations()
+		if err != nil {
+			return fmt.Errorf("CASE: %w", err)
+		}
+		alts = append(alts, combined...)
+	}
+	b.cases = append(b.cases, alts)
+	return nil
+}
+
+// combinations lists the sets of conditions that can hold together in
+// bindings that stand for one way of a CASE or an OR to hold, one set
+// for every combination of the ways of the CASEs and ORs within.
+func (b bindings) combinations() (alternatives, error) {
+	if len(b.links) > 0 || len(b.aggregates) > 0 || len(b.windows) > 0 {
+		return nil, fmt.Errorf("only comparisons between columns and values are supported")
+	}
+	out := alternatives{b.conditions}
+	for _, alts := range b.cases {
+		var next alternatives
+		for _, conds := range out {
+			for _, alt := range alts {
+				next = append(next, append(slices.Clip(conds), alt...))
+			}
+		}
+		out = next
+	}
+	return out, nil
+}
+
+// expand splits a set of constraints into one set for each combination
+// of the alternatives of the CASE comparisons of an arm, so that the
+// rows of the table reach every branch. Combinations that leave a column
+// without a value are dropped, and so are the alternatives that do not
+// constrain the table, but for one set that keeps the rows unchanged.
+func (c constrainer) expand(set map[string][]types.Constraints, cases []alternatives) ([]map[string][]types.Constraints, error) {
+	sets := []map[string][]types.Constraints{set}
+	for _, alts := range cases {
+		var next []map[string][]types.Constraints
+		for _, set := range sets {
+			unchanged := false
+			for _, alt := range alts {
+				branch := cloneSet(set)
+				if err := c.add(branch, bindings{conditions: alt}); err != nil {
+					return nil, fmt.Errorf("CASE: %w", err)
+				}
+				if maps.EqualFunc(branch, set, func(a, b []types.Constraints) bool { return len(a) == len(b) }) {
+					if unchanged {
+						continue
+					}
+					unchanged = true
+				}
+				if c.feasible(branch) {
+					next = append(next, branch)
+				}
+			}
+		}
+		if len(next) == 0 {
+			return nil, fmt.Errorf("no branch of the CASE can be reached")
+		}
+		sets = next
+	}
+	return sets, nil
+}
+
+// feasible reports whether every column of a set of constraints is left
+// with a value to take.
+func (c constrainer) feasible(set map[string][]types.Constraints) bool {
+	for name, constraints := range set {
+		domain, err := solver.NewDomain(c.t.Types[name])
+		if err != nil {
+			return false
+		}
+		for _, cons := range constraints {
+			if err := cons.Apply(domain); err != nil {
+				return false
+			}
+		}
+		if domain.IsNull() {
+			continue
+		}
+		if _, err := domain.RandomValue(rand.New(rand.NewSource(0))); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// cloneSet copies a set of constraints, so that constraints appended to
+// the copy do not end up in the original.
+func cloneSet(set map[string][]types.Constraints) map[string][]types.Constraints {
+	out := maps.Clone(set)
+	for name, cons := range out {
+		out[name] = slices.Clip(cons)
+	}
+	return out
+}
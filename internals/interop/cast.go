@@ -0,0 +1,99 @@
+package interop
+
+import (
+	"fmt"
+
+	"github.com/phdah/sql-tdg/internals/parser"
+	"github.com/phdah/sql-tdg/internals/solver"
+	"github.com/phdah/sql-tdg/internals/types"
+)
+
+// castTypes maps the SQL types a column can be cast to, in any of the
+// dialects, onto the column type whose values they hold. The numeric
+// types all hold ints, as a comparison on a number is rounded to the
+// ints that pass it. DATE is only read on a timestamp column, where it
+// stands for the day of the timestamp.
+var castTypes = map[string]types.Type{
+	"tinyint": types.IntType, "smallint": types.IntType, "int": types.IntType,
+	"integer": types.IntType, "bigint": types.IntType, "byte": types.IntType,
+	"short": types.IntType, "long": types.IntType, "float": types.IntType,
+	"double": types.IntType, "real": types.IntType, "decimal": types.IntType,
+	"numeric": types.IntType, "int2": types.IntType, "int4": types.IntType,
+	"int8": types.IntType, "float4": types.IntType, "float8": types.IntType,
+	"int64": types.IntType, "float64": types.IntType, "bignumeric": types.IntType,
+	"bigdecimal": types.IntType, "number": types.IntType, "hugeint": types.IntType,
+
+	"string": types.StringType, "varchar": types.StringType, "char": types.StringType,
+	"text": types.StringType,
+
+	"boolean": types.BoolType, "bool": types.BoolType,
+
+	"timestamp": types.TimestampType, "timestamp_ntz": types.TimestampType,
+	"timestamp_ltz": types.TimestampType, "timestamp_tz": types.TimestampType,
+	"timestamptz": types.TimestampType, "datetime": types.TimestampType,
+	"date": types.TimestampType,
+}
+
+// secondsPerDay is the length of a day in the seconds timestamps are
+// stored in.
+const secondsPerDay = 24 * 60 * 60
+
+// castConstraint builds the constraint of a condition on a cast column.
+// A cast to the type the column already holds changes nothing, and a
+// timestamp cast to DATE compares whole days, so = '2024-01-01' keeps
+// the timestamps to that day.
+func castConstraint(typ types.Type, c parser.ConditionsIR, operands []parser.LiteralIR) (types.Constraints, error) {
+	cast := c.Cast
+	c.Cast = ""
+	if cast == "date" && typ == types.TimestampType {
+		return dayConstraint(c, operands)
+	}
+	if castTypes[cast] != typ {
+		return nil, fmt.Errorf("CAST of a %s column to %s is not supported", typ, cast)
+	}
+	return MakeConstraint(typ, c, operands)
+}
+
+// dayConstraint builds the constraint of a condition on a timestamp cast
+// to DATE. Each date stands for the seconds of its day, so a timestamp
+// is on a date when it is between its first and its last second.
+func dayConstraint(c parser.ConditionsIR, operands []parser.LiteralIR) (types.Constraints, error) {
+	values, err := literals(c, operands, "date", timestamp)
+	if err != nil {
+		return nil, err
+	}
+	op, err := effectiveOp(c)
+	if err != nil {
+		return nil, err
+	}
+	first := func(v int) int { return v - (v%secondsPerDay+secondsPerDay)%secondsPerDay }
+	last := func(v int) int { return first(v) + secondsPerDay - 1 }
+	switch op {
+	case "=":
+		return solver.IntBetween{Min: first(values[0]), Max: last(values[0])}, nil
+	case "!=", "<>":
+		return solver.IntNotBetween{Min: first(values[0]), Max: last(values[0])}, nil
+	case "<":
+		return solver.IntLt{Value: first(values[0])}, nil
+	case "<=":
+		return solver.IntLte{Value: last(values[0])}, nil
+	case ">":
+		return solver.IntGt{Value: last(values[0])}, nil
+	case ">=":
+		return solver.IntGte{Value: first(values[0])}, nil
+	case "BETWEEN":
+		return solver.IntBetween{Min: first(values[0]), Max: last(values[1])}, nil
+	case "NOT BETWEEN":
+		return solver.IntNotBetween{Min: first(values[0]), Max: last(values[1])}, nil
+	case "IN", "NOT IN":
+		if len(values) != 1 {
+			return nil, fmt.Errorf("%s over more than one date is not supported", op)
+		}
+		if op == "IN" {
+			return solver.IntBetween{Min: first(values[0]), Max: last(values[0])}, nil
+		}
+		return solver.IntNotBetween{Min: first(values[0]), Max: last(values[0])}, nil
+	default:
+		return nil, fmt.Errorf("bad date op %q", op)
+	}
+}
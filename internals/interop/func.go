@@ -0,0 +1,32 @@
+package interop
+
+import (
+	"fmt"
+
+	"github.com/phdah/sql-tdg/internals/parser"
+)
+
+// dateFuncs are the functions that truncate a timestamp to its date, the
+// same as a cast to DATE.
+var dateFuncs = map[string]bool{
+	"DATE":    true,
+	"TO_DATE": true,
+}
+
+// bindFunc binds a condition on a function call. The functions that act
+// as a cast, such as DATE(ts), are bound as a cast of their column, the
+// window functions to the columns of their window, and the others are
+// not supported.
+func (s *scope) bindFunc(p *parser.PredIR, b *bindings) error {
+	c := p.Condition()
+	f := c.Func
+	if f.Window != nil {
+		return s.bindWindow(p, b)
+	}
+	if dateFuncs[f.Name] && len(f.Args) == 1 && f.Args[0].Column != nil {
+		col := *f.Args[0].Column
+		c.Left, c.Cast, c.Func = parser.LeftIR(col.String()), "date", nil
+		return s.bindComparison(col, c, p.Literals, b)
+	}
+	return fmt.Errorf("condition %s %s %s: function %s is not supported", c.Left, c.Op, c.Right, f.Name)
+}
@@ -0,0 +1,257 @@
+package interop
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"strconv"
+
+	"github.com/phdah/sql-tdg/internals/parser"
+	"github.com/phdah/sql-tdg/internals/solver"
+	"github.com/phdah/sql-tdg/internals/types"
+)
+
+// groupSize returns the smallest and the largest number of rows a group
+// can have for the COUNT conditions of a HAVING clause to hold.
+func groupSize(aggregates []boundCondition) (int, int, error) {
+	lo, hi := 1, math.MaxInt
+	for _, a := range aggregates {
+		if a.Aggregate != "COUNT" {
+			continue
+		}
+		op, n, err := intOperands(a.ConditionsIR, a.operands)
+		if err != nil {
+			return 0, 0, fmt.Errorf("COUNT(%s): %w", a.Left, err)
+		}
+		switch op {
+		case "=":
+			lo, hi = max(lo, n[0]), min(hi, n[0])
+		case ">":
+			lo = max(lo, n[0]+1)
+		case ">=":
+			lo = max(lo, n[0])
+		case "<":
+			hi = min(hi, n[0]-1)
+		case "<=":
+			hi = min(hi, n[0])
+		case "BETWEEN":
+			lo, hi = max(lo, n[0]), min(hi, n[1])
+		default:
+			return 0, 0, fmt.Errorf("COUNT(%s) %s is not supported", a.Left, op)
+		}
+	}
+	if lo > hi {
+		return 0, 0, fmt.Errorf("the COUNT conditions leave no possible group size")
+	}
+	return lo, hi, nil
+}
+
+// group splits the rows of a grouped query into groups, each with its
+// own value for the keys of the table, and with enough rows for the
+// COUNT conditions of the HAVING clause to hold. The other aggregates
+// turn into conditions that every row of a group meets, which is
+// enough for the aggregate to meet it as well: the SUM of k values
+// above 1000 / k is above 1000. A SUM of ints equal to a number the
+// size of the groups does not divide is the exception, as one row of
+// every group makes up the remainder, so every row gets a set of
+// constraints of its own.
+func (c constrainer) group(set map[string][]types.Constraints, b bindings) ([]map[string][]types.Constraints, error) {
+	var keys []*types.Column
+	for _, key := range b.groupBy {
+		col, err := c.column(key)
+		if err != nil {
+			return nil, fmt.Errorf("GROUP BY: %w", err)
+		}
+		if col != nil {
+			keys = append(keys, col)
+		}
+	}
+
+	lo, hi, err := groupSize(b.aggregates)
+	if err != nil {
+		return nil, err
+	}
+	size, groups := c.split(len(keys) > 0, lo, hi)
+
+	// most holds the conditions of the spread SUMs on all the rows of a
+	// group but one, and one those on the remaining row
+	var most, one bindings
+	for _, a := range b.aggregates {
+		col, err := c.column(a.columnRef)
+		if err != nil {
+			return nil, fmt.Errorf("%s(%s): %w", a.Aggregate, a.Left, err)
+		}
+		if col == nil {
+			continue
+		}
+		var cond, rest boundCondition
+		switch a.Aggregate {
+		case "COUNT":
+			// Only the values that are not NULL are counted
+			cond = boundCondition{
+				columnRef:    a.columnRef,
+				ConditionsIR: parser.ConditionsIR{Left: a.Left, Op: "IS NULL", Negated: true},
+				pos:          a.pos,
+			}
+		default:
+			cond, rest, err = rowConditions(a, c.t.Types[col.Name], size)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if rest.Op != "" {
+			most.conditions = append(most.conditions, cond)
+			one.conditions = append(one.conditions, rest)
+			continue
+		}
+		if err := c.add(set, bindings{conditions: []boundCondition{cond}}); err != nil {
+			return nil, err
+		}
+	}
+
+	sets, err := c.splitKeys(set, keys, groups, "GROUP BY")
+	if err != nil || len(one.conditions) == 0 {
+		return sets, err
+	}
+	// The rows are dealt over the sets in turn, so the row of every group
+	// that makes up the remainder comes first and the others follow
+	rows := make([]map[string][]types.Constraints, 0, size*groups)
+	for i := range size {
+		row := most
+		if i == 0 {
+			row = one
+		}
+		for _, group := range sets {
+			group = cloneSet(group)
+			if err := c.add(group, row); err != nil {
+				return nil, err
+			}
+			rows = append(rows, group)
+		}
+	}
+	return rows, nil
+}
+
+// split picks the size of the groups of rows, within the given bounds,
+// and their number. A table with keys gets at least two groups, and as
+// many as the LIMIT of the query needs, while a table without any is a
+// single group. The number of rows of the table is rounded up to fill
+// every group.
+func (c constrainer) split(keyed bool, lo, hi int) (int, int) {
+	size := c.t.Dim.Rows
+	if keyed {
+		size = max(1, size/2)
+	}
+	size = min(max(size, lo), hi)
+	groups := 1
+	if keyed {
+		groups = max((c.t.Dim.Rows+size-1)/size, c.minRows)
+	}
+	c.t.Dim.Rows = groups * size
+	return size, groups
+}
+
+// splitKeys copies a set of constraints for each of n groups, and gives
+// the keys of the table a different value in every group. The clause
+// the keys come from is named in the errors.
+func (c constrainer) splitKeys(set map[string][]types.Constraints, keys []*types.Column, n int, clause string) ([]map[string][]types.Constraints, error) {
+	sets := make([]map[string][]types.Constraints, n)
+	for g := range sets {
+		sets[g] = cloneSet(set)
+	}
+	for _, key := range keys {
+		values, err := distinctValues(c.t.Types[key.Name], set[key.Name], n)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", clause, key.Name, err)
+		}
+		for g, v := range values {
+			cons, err := keyConstraint(v, false)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", clause, key.Name, err)
+			}
+			sets[g][key.Name] = append(sets[g][key.Name], cons)
+		}
+	}
+	return sets, nil
+}
+
+// rowConditions returns the conditions the rows of a group of the given
+// size have to meet for a condition on the SUM, AVG, MIN or MAX of the
+// group to hold: the first on all the rows of the group, or on all of
+// them but one when the second is not empty, and the second on that
+// one row. A SUM of ints equal to a number the size does not divide is
+// spread that way, as the SUM of 7 over 4 rows is 1 + 1 + 1 + 4.
+func rowConditions(a boundCondition, typ types.Type, size int) (boundCondition, boundCondition, error) {
+	op, err := effectiveOp(a.ConditionsIR)
+	if err != nil {
+		return boundCondition{}, boundCondition{}, err
+	}
+	switch op {
+	case "=", "<", "<=", ">", ">=":
+	default:
+		return boundCondition{}, boundCondition{}, fmt.Errorf("%s(%s) %s is not supported", a.Aggregate, a.Left, op)
+	}
+	cond := boundCondition{
+		columnRef:    a.columnRef,
+		ConditionsIR: parser.ConditionsIR{Left: a.Left, Op: op, Right: a.Right},
+		operands:     a.operands,
+		pos:          a.pos,
+	}
+	if a.Aggregate != "SUM" {
+		return cond, boundCondition{}, nil
+	}
+	values, err := literals(a.ConditionsIR, a.operands, "number", number)
+	if err != nil {
+		return boundCondition{}, boundCondition{}, fmt.Errorf("SUM(%s): %w", a.Left, err)
+	}
+	v := values[0]
+	n := int(v)
+	if op != "=" || typ != types.IntType || float64(n) != v || n%size == 0 {
+		share := v / float64(size)
+		cond.Right = parser.RightIR(strconv.FormatFloat(share, 'g', -1, 64))
+		cond.operands = []parser.LiteralIR{{Kind: parser.FloatLiteral, Float: share}}
+		return cond, boundCondition{}, nil
+	}
+	rest := cond
+	cond.Right, cond.operands = intOperand(n / size)
+	rest.Right, rest.operands = intOperand(n/size + n%size)
+	return cond, rest, nil
+}
+
+// intOperand is the operand of a condition on the int n, as text and as
+// a literal.
+func intOperand(n int) (parser.RightIR, []parser.LiteralIR) {
+	return parser.RightIR(strconv.Itoa(n)), []parser.LiteralIR{{Kind: parser.IntLiteral, Int: n, Float: float64(n)}}
+}
+
+// distinctValues draws n different values that meet the constraints. It
+// uses a fixed seed, so that every table grouped on the same key with
+// the same constraints gets the same values.
+func distinctValues(typ types.Type, constraints []types.Constraints, n int) ([]any, error) {
+	rng := rand.New(rand.NewSource(0))
+	constraints = slices.Clip(constraints)
+	values := make([]any, 0, n)
+	for len(values) < n {
+		domain, err := solver.NewDomain(typ)
+		if err != nil {
+			return nil, err
+		}
+		for _, cons := range constraints {
+			if err := cons.Apply(domain); err != nil {
+				return nil, fmt.Errorf("not enough values for %d groups", n)
+			}
+		}
+		v, err := domain.RandomValue(rng)
+		if err != nil {
+			return nil, fmt.Errorf("not enough values for %d groups", n)
+		}
+		values = append(values, v)
+		exclude, err := keyConstraint(v, true)
+		if err != nil {
+			return nil, err
+		}
+		constraints = append(constraints, exclude)
+	}
+	return values, nil
+}
@@ -2,7 +2,11 @@ package interop
 
 import (
 	"fmt"
-	"strconv"
+	"math"
+	"math/rand"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/phdah/sql-tdg/internals/parser"
 	"github.com/phdah/sql-tdg/internals/solver"
@@ -16,85 +20,728 @@ type Query struct {
 
 func Wrap(q *parser.Query) Query { return Query{q} }
 
+// AddConditions adds the conditions of the query that concern the given
+// table as constraints on its columns, along with those of the CTEs and
+// subqueries it reads. The keys linking the table to the other tables
+// of the query are drawn from a shared pool, so that the rows match.
+//
+// The rows are split into a branch for each arm of a compound query,
+// term of an OR and branch of a CASE, and into groups or partitions
+// for a GROUP BY or a QUALIFY. The table gets enough rows for the LIMIT
+// and OFFSET. An error about a predicate is located at the predicate,
+// for parser.Diagnose.
 func (q *Query) AddConditions(t *table.Table) error {
+	ctes := withCTEs(q.With, nil)
+	arms := q.Arms()
+	scopes := make([]*scope, len(arms))
+	binds := make([]bindings, len(arms))
+	for i, arm := range arms {
+		s, err := newArmScope(arm, ctes)
+		if err != nil {
+			return err
+		}
+		if err := s.bind(arm, &binds[i]); err != nil {
+			return err
+		}
+		scopes[i] = s
+	}
+
+	c := constrainer{
+		t: t, target: t.Name, idx: make(map[string]int, len(t.Schema)), minRows: q.MinRows(), keys: keyPool,
+	}
+	for _, b := range binds {
+		if len(b.groupBy) > 0 || len(b.aggregates) > 0 || len(b.windows) > 0 {
+			c.keys = 1
+		}
+	}
 	// quick index by column name
-	idx := make(map[string]int, len(t.Schema))
 	for i := range t.Schema {
-		idx[t.Schema[i].Name] = i
+		c.idx[t.Schema[i].Name] = i
+	}
+	if c.target == "" {
+		c.target = scopes[0].base()
+	}
+	reads := func(arm int) bool {
+		return scopes[arm].reads(c.target) || binds[arm].reads(c.target)
+	}
+	var readBy []int
+	for i := range arms {
+		if reads(i) {
+			readBy = append(readBy, i)
+		}
+	}
+	if t.Name != "" && len(readBy) == 0 {
+		return fmt.Errorf("table %q is not in the query", t.Name)
 	}
 
-	constraints := q.GetConditions()
-	colTypes := t.Types
-	for _, c := range constraints {
-		i, ok := idx[string(c.Left)]
-		if !ok {
-			return fmt.Errorf("unknown column %q", c.Left)
+	var sets []map[string][]types.Constraints
+	var grouping bindings
+	for _, branch := range q.Branches() {
+		if !slices.ContainsFunc(branch.Include, reads) {
+			continue
+		}
+		set := make(map[string][]types.Constraints)
+		for _, i := range branch.Include {
+			if err := c.add(set, binds[i]); err != nil {
+				return err
+			}
+			grouping.groupBy = append(grouping.groupBy, binds[i].groupBy...)
+			grouping.aggregates = append(grouping.aggregates, binds[i].aggregates...)
+			grouping.windows = append(grouping.windows, binds[i].windows...)
+		}
+		for _, i := range branch.Exclude {
+			if err := c.exclude(set, binds[i]); err != nil {
+				return err
+			}
+		}
+		var cases []alternatives
+		for _, i := range branch.Include {
+			cases = append(cases, binds[i].cases...)
+		}
+		expanded, err := c.expand(set, cases)
+		if err != nil {
+			return err
 		}
+		spread := make(map[string]bool)
+		for _, i := range branch.Include {
+			if expanded, err = c.spread(expanded, binds[i], spread); err != nil {
+				return err
+			}
+		}
+		sets = append(sets, expanded...)
+	}
+	grouped := len(grouping.groupBy) > 0 || len(grouping.aggregates) > 0
+	windowed := len(grouping.windows) > 0
+	switch {
+	case (grouped || windowed) && len(sets) > 1:
+		clause := "GROUP BY"
+		if !grouped {
+			clause = "a window function"
+		}
+		return fmt.Errorf("%s over several branches of rows, as of a compound query or a CASE, is not supported", clause)
+	case grouped && windowed:
+		return fmt.Errorf("window functions in a grouped query are not supported")
+	case grouped:
+		grouped, err := c.group(sets[0], grouping)
+		if err != nil {
+			return err
+		}
+		sets = grouped
+	case windowed:
+		partitioned, err := c.window(sets[0], grouping.windows)
+		if err != nil {
+			return err
+		}
+		sets = partitioned
+	}
+	if len(sets) == 0 {
+		// The table is only read by arms the rows have to stay out of
+		set := make(map[string][]types.Constraints)
+		for _, i := range readBy {
+			if err := c.exclude(set, binds[i]); err != nil {
+				return err
+			}
+		}
+		sets = append(sets, set)
+	}
+
+	if !grouped {
+		// The groups are the rows of a grouped query, so the keys of
+		// the groups are distinct and their number covers the LIMIT
+		// already
+		t.Dim.Rows = max(t.Dim.Rows, c.minRows)
+		if c.spreads(binds) {
+			// Every branch needs a row for every key value to be taken
+			t.Dim.Rows = max(t.Dim.Rows, len(sets))
+		}
+		if err := c.distinctOrder(q, scopes, sets); err != nil {
+			return err
+		}
+	}
+
+	if len(sets) > 1 {
+		t.Branches = sets
+		return nil
+	}
+	for i := range t.Schema {
 		col := &t.Schema[i]
-		cons, err := MakeConstraint(colTypes[string(c.Left)], c)
+		col.Constraints = append(col.Constraints, sets[0][col.Name]...)
+	}
+	return nil
+}
+
+// constrainer turns the bound conditions of a query into constraints on
+// the columns of a table.
+type constrainer struct {
+	t      *table.Table
+	target string
+	idx    map[string]int
+
+	// minRows is the number of rows the result needs for the LIMIT and
+	// OFFSET of the query
+	minRows int
+	// keys is the number of values the keys of a semi-join are drawn
+	// from, which is 1 when the rows are grouped or partitioned, as their
+	// sets of constraints can not be split any further
+	keys int
+}
+
+// column finds the column of the table a reference points to, if any.
+func (c constrainer) column(ref columnRef) (*types.Column, error) {
+	if !ref.in(c.target) {
+		return nil, nil
+	}
+	i, ok := c.idx[ref.column]
+	if !ok {
+		// Unqualified columns may belong to one of the other tables
+		if ref.table == "" {
+			return nil, nil
+		}
+		return nil, c.unknownColumn(ref.column, ref.column)
+	}
+	return &c.t.Schema[i], nil
+}
+
+// unknownColumn reports a column the table does not have, along with
+// the columns it has that were likely meant.
+func (c constrainer) unknownColumn(name, column string) error {
+	names := make([]string, 0, len(c.t.Schema))
+	for _, col := range c.t.Schema {
+		names = append(names, col.Name)
+	}
+	return &parser.Diagnostic{
+		Err:         fmt.Errorf("unknown column %q", name),
+		Suggestions: parser.Suggest(column, names),
+	}
+}
+
+// add adds the constraints of the conditions and the subquery keys of
+// an arm to the set of constraints by column name.
+func (c constrainer) add(set map[string][]types.Constraints, b bindings) error {
+	for _, cond := range b.conditions {
+		col, err := c.column(cond.columnRef)
 		if err != nil {
-			return fmt.Errorf("column %s: %w", c.Left, err)
+			return parser.At(cond.pos, c.unknownColumn(string(cond.Left), cond.column))
+		}
+		if col == nil {
+			continue
+		}
+		cons, err := MakeConstraint(c.t.Types[col.Name], cond.ConditionsIR, cond.operands)
+		if err != nil {
+			return parser.At(cond.pos, fmt.Errorf("column %s: %w", cond.Left, err))
+		}
+		if _, isNull := cons.(solver.IsNull); isNull && !col.Nullable {
+			return parser.At(cond.pos, fmt.Errorf("column %s: IS NULL on a column that is not nullable", cond.Left))
+		}
+		set[col.Name] = append(set[col.Name], cons)
+	}
+
+	for _, l := range b.links {
+		for i, key := range []columnRef{l.outer, l.inner} {
+			col, err := c.column(key)
+			if err != nil {
+				return parser.At(l.pos, err)
+			}
+			if col == nil {
+				continue
+			}
+			values, err := l.pool(c.t.Types[col.Name], b.conditions, c.poolSize(l))
+			if err != nil {
+				return parser.At(l.pos, fmt.Errorf("key %s: %w", key.column, err))
+			}
+			cons, err := poolConstraint(values, l.anti && i == 0)
+			if err != nil {
+				return parser.At(l.pos, fmt.Errorf("key %s: %w", key.column, err))
+			}
+			set[col.Name] = append(set[col.Name], cons)
 		}
-		col.Constraints = append(col.Constraints, cons)
 	}
 	return nil
 }
 
-func MakeConstraint(typ types.Type, c parser.ConditionsIR) (types.Constraints, error) {
-	switch typ {
-	case types.IntType:
-		n, err := strconv.Atoi(string(c.Right))
+// exclude keeps rows out of an arm by negating its first condition. A
+// row that fails one of the conditions of an arm does not come out of
+// it, whichever table the condition is on. An arm whose conditions are
+// all ORs or CASEs is left by failing every alternative of the first
+// of them, each by its first condition.
+func (c constrainer) exclude(set map[string][]types.Constraints, b bindings) error {
+	if len(b.conditions) > 0 {
+		return c.add(set, bindings{conditions: []boundCondition{negate(b.conditions[0])}})
+	}
+	if len(b.cases) == 0 {
+		return fmt.Errorf("EXCEPT over an arm without conditions leaves no rows")
+	}
+	var negated []boundCondition
+	for _, alt := range b.cases[0] {
+		if len(alt) == 0 {
+			return fmt.Errorf("EXCEPT over an arm with an OR or a CASE that always holds leaves no rows")
+		}
+		negated = append(negated, negate(alt[0]))
+	}
+	return c.add(set, bindings{conditions: negated})
+}
+
+func negate(cond boundCondition) boundCondition {
+	cond.Negated = !cond.Negated
+	return cond
+}
+
+// keyPool is the number of values the keys of a semi-join are drawn
+// from, which leaves room for a key that is distinct for the ORDER BY.
+const keyPool = 4
+
+// poolSize returns the number of values the keys of a link are drawn
+// from. The outer key of an anti-join only has to stay clear of the
+// inner one, so a single value does.
+func (c constrainer) poolSize(l keyLink) int {
+	if l.anti {
+		return 1
+	}
+	return c.keys
+}
+
+// spreadKey returns the key of a link that the rows of the table are
+// spread over, which is the inner key of a semi-join, or either key of a
+// join, or nil when the table has no such key.
+func (c constrainer) spreadKey(l keyLink) *types.Column {
+	if c.poolSize(l) == 1 {
+		return nil
+	}
+	keys := []columnRef{l.inner}
+	if l.join {
+		keys = append(keys, l.outer)
+	}
+	for _, key := range keys {
+		// A key that fails to resolve was reported when it was added
+		if col, _ := c.column(key); col != nil {
+			return col
+		}
+	}
+	return nil
+}
+
+// spreads reports whether the rows of the table are spread over the key
+// values of a semi-join or a join.
+func (c constrainer) spreads(binds []bindings) bool {
+	for _, b := range binds {
+		for _, l := range b.links {
+			if c.spreadKey(l) != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// spread splits the sets of constraints of the table that a semi-join
+// reads into one set for each value of its key, so that the rows take
+// the values in turn and cover all of them. The outer key may then take
+// any of the values and still find its match. Both tables of a join are
+// spread, as the rows of either have to match. The keys in done, which
+// another arm of an INTERSECT may have spread already, are left alone.
+func (c constrainer) spread(sets []map[string][]types.Constraints, b bindings, done map[string]bool) ([]map[string][]types.Constraints, error) {
+	for _, l := range b.links {
+		col := c.spreadKey(l)
+		if col == nil || done[col.Name] {
+			continue
+		}
+		done[col.Name] = true
+		values, err := l.pool(c.t.Types[col.Name], b.conditions, c.poolSize(l))
 		if err != nil {
-			return nil, fmt.Errorf("int parse: %w", err)
+			return nil, parser.At(l.pos, fmt.Errorf("key %s: %w", col.Name, err))
 		}
-		switch c.Op {
-		case "=":
-			return solver.IntEq{Value: n}, nil
-		case "!=":
-			return solver.IntNEq{Value: n}, nil
-		case ">":
-			return solver.IntGt{Value: n}, nil
-		case ">=":
-			return solver.IntGte{Value: n}, nil
-		case "<":
-			return solver.IntLt{Value: n}, nil
-		case "<=":
-			return solver.IntLte{Value: n}, nil
+		next := make([]map[string][]types.Constraints, 0, len(sets)*len(values))
+		for _, set := range sets {
+			for _, v := range values {
+				cons, err := keyConstraint(v, false)
+				if err != nil {
+					return nil, parser.At(l.pos, fmt.Errorf("key %s: %w", col.Name, err))
+				}
+				branch := cloneSet(set)
+				branch[col.Name] = append(branch[col.Name], cons)
+				next = append(next, branch)
+			}
+		}
+		sets = next
+	}
+	return sets, nil
+}
+
+// pool draws the values the keys of a link are taken from, up to size of
+// them. The keys of a semi-join share their conditions, while under an
+// anti-join the outer key only has to stay clear of the inner one. Each
+// table of the link draws the values on its own, so they are drawn with
+// a fixed seed from the same conditions to come out the same.
+func (l keyLink) pool(typ types.Type, conditions []boundCondition, size int) ([]any, error) {
+	domain, err := solver.NewDomain(typ)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range conditions {
+		if !c.is(l.inner) && (l.anti || !c.is(l.outer)) {
+			continue
+		}
+		cons, err := MakeConstraint(typ, c.ConditionsIR, c.operands)
+		if err != nil {
+			return nil, err
+		}
+		if err := cons.Apply(domain); err != nil {
+			return nil, err
+		}
+	}
+	if domain.IsNull() {
+		return nil, fmt.Errorf("a NULL key never matches")
+	}
+	rng := rand.New(rand.NewSource(0))
+	var values []any
+	seen := make(map[any]bool)
+	// A domain with fewer values than the pool runs out of new ones
+	for range size * 10 {
+		v, err := domain.RandomValue(rng)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[v] {
+			seen[v] = true
+			values = append(values, v)
+		}
+		if len(values) == size {
+			break
+		}
+	}
+	return values, nil
+}
+
+// keyConstraint pins a key to the given value, or keeps it away from the
+// value when exclude is set.
+func keyConstraint(value any, exclude bool) (types.Constraints, error) {
+	switch v := value.(type) {
+	case int:
+		if exclude {
+			return solver.IntNEq{Value: v}, nil
+		}
+		return solver.IntEq{Value: v}, nil
+	case time.Time:
+		if exclude {
+			return solver.IntNEq{Value: int(v.Unix())}, nil
+		}
+		return solver.IntEq{Value: int(v.Unix())}, nil
+	case string:
+		if exclude {
+			return solver.StringNEq{Value: v}, nil
+		}
+		return solver.StringEq{Value: v}, nil
+	case bool:
+		if v != exclude {
+			return solver.BoolTrue{}, nil
+		}
+		return solver.BoolFalse{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key value %T", value)
+	}
+}
+
+// poolConstraint keeps a key to one of the values of a pool, or away
+// from all of them when exclude is set.
+func poolConstraint(values []any, exclude bool) (types.Constraints, error) {
+	if len(values) == 1 {
+		return keyConstraint(values[0], exclude)
+	}
+	var ints []int
+	var strs []string
+	for _, value := range values {
+		switch v := value.(type) {
+		case int:
+			ints = append(ints, v)
+		case time.Time:
+			ints = append(ints, int(v.Unix()))
+		case string:
+			strs = append(strs, v)
 		default:
-			return nil, fmt.Errorf("bad int op %q", c.Op)
+			return nil, fmt.Errorf("unsupported key value %T", value)
+		}
+	}
+	switch {
+	case strs != nil && exclude:
+		return solver.StringNotIn{Values: strs}, nil
+	case strs != nil:
+		return solver.StringIn{Values: strs}, nil
+	case exclude:
+		return solver.IntNotIn{Values: ints}, nil
+	default:
+		return solver.IntIn{Values: ints}, nil
+	}
+}
+
+// complement maps a comparison operator onto the operator that holds
+// exactly when the original one does not.
+var complement = map[parser.OpIR]parser.OpIR{
+	"=":       "!=",
+	"!=":      "=",
+	"<>":      "=",
+	">":       "<=",
+	">=":      "<",
+	"<":       ">=",
+	"<=":      ">",
+	"IN":      "NOT IN",
+	"BETWEEN": "NOT BETWEEN",
+	"LIKE":    "NOT LIKE",
+	"ILIKE":   "NOT ILIKE",
+}
+
+// effectiveOp returns the operator a condition has to be applied with,
+// replacing the operator by its complement when the condition is negated.
+func effectiveOp(c parser.ConditionsIR) (parser.OpIR, error) {
+	if !c.Negated {
+		return c.Op, nil
+	}
+	op, ok := complement[c.Op]
+	if !ok {
+		return "", fmt.Errorf("cannot negate op %q", c.Op)
+	}
+	return op, nil
+}
+
+// literals reads the operands of a condition, Values for list
+// predicates and Right otherwise, from their typed literals. read
+// reports false for a literal of the wrong kind.
+func literals[T any](c parser.ConditionsIR, operands []parser.LiteralIR, kind string, read func(parser.LiteralIR) (T, bool)) ([]T, error) {
+	raw := c.Values
+	if raw == nil {
+		raw = []parser.RightIR{c.Right}
+	}
+	if len(operands) != len(raw) {
+		text := make([]string, 0, len(raw))
+		for _, r := range raw {
+			text = append(text, string(r))
+		}
+		return nil, fmt.Errorf("expected %s literals, got %s", kind, strings.Join(text, ", "))
+	}
+	values := make([]T, 0, len(operands))
+	for i, lit := range operands {
+		v, ok := read(lit)
+		if !ok {
+			return nil, fmt.Errorf("expected a %s literal, got %s", kind, raw[i])
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func number(l parser.LiteralIR) (float64, bool) {
+	return l.Float, l.Kind == parser.IntLiteral || l.Kind == parser.FloatLiteral
+}
+
+func integer(l parser.LiteralIR) (int, bool) {
+	return l.Int, l.Kind == parser.IntLiteral
+}
+
+func timestamp(l parser.LiteralIR) (int, bool) {
+	return int(l.Time.Unix()), l.Kind == parser.TimestampLiteral
+}
+
+// str reads a string literal, including one that reads as a date.
+func str(l parser.LiteralIR) (string, bool) {
+	return l.Str, l.Kind == parser.StringLiteral || l.Kind == parser.TimestampLiteral
+}
+
+func boolean(l parser.LiteralIR) (bool, bool) {
+	return l.Bool, l.Kind == parser.BoolLiteral
+}
+
+// intConstraint builds the interval constraint of a condition from its
+// operands. It is shared by the int and the timestamp columns, which
+// only differ in the literals they are compared with.
+func intConstraint(op parser.OpIR, values []int, kind string) (types.Constraints, error) {
+	switch op {
+	case "=":
+		return solver.IntEq{Value: values[0]}, nil
+	case "!=", "<>":
+		return solver.IntNEq{Value: values[0]}, nil
+	case ">":
+		return solver.IntGt{Value: values[0]}, nil
+	case ">=":
+		return solver.IntGte{Value: values[0]}, nil
+	case "<":
+		return solver.IntLt{Value: values[0]}, nil
+	case "<=":
+		return solver.IntLte{Value: values[0]}, nil
+	case "IN":
+		return solver.IntIn{Values: values}, nil
+	case "NOT IN":
+		return solver.IntNotIn{Values: values}, nil
+	case "BETWEEN":
+		return solver.IntBetween{Min: values[0], Max: values[1]}, nil
+	case "NOT BETWEEN":
+		return solver.IntNotBetween{Min: values[0], Max: values[1]}, nil
+	default:
+		return nil, fmt.Errorf("bad %s op %q", kind, op)
+	}
+}
+
+// toInt converts an integral number into an int.
+func toInt(f float64) (int, error) {
+	if f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, fmt.Errorf("%v is out of range", f)
+	}
+	return int(f), nil
+}
+
+// intOperands returns the operator and the int operands of a condition
+// on ints. Int literals are taken as they are, since a float only holds
+// the ints up to 2^53, and the other numbers are rounded to the ints
+// that pass the condition.
+func intOperands(c parser.ConditionsIR, operands []parser.LiteralIR) (parser.OpIR, []int, error) {
+	op, err := effectiveOp(c)
+	if err != nil {
+		return "", nil, err
+	}
+	if ints, err := literals(c, operands, "int", integer); err == nil {
+		return op, ints, nil
+	}
+	values, err := literals(c, operands, "number", number)
+	if err != nil {
+		return "", nil, err
+	}
+	return roundToInts(op, values)
+}
+
+// roundToInts maps numeric operands onto the int operands that select
+// the same ints. Bounds that are not integral are rounded inwards, so
+// a < 9.99 becomes a <= 9, and list values that are not integral are
+// dropped, as no int can equal them.
+func roundToInts(op parser.OpIR, values []float64) (parser.OpIR, []int, error) {
+	integral := func(f float64) bool { return f == math.Trunc(f) }
+	var rounded []float64
+	switch op {
+	case "=":
+		if !integral(values[0]) {
+			return "", nil, fmt.Errorf("no int is equal to %v", values[0])
+		}
+		rounded = values
+	case "!=", "<>":
+		if !integral(values[0]) {
+			// Every int is different, so nothing is excluded
+			return "NOT IN", []int{}, nil
+		}
+		rounded = values
+	case "<", "<=":
+		if !integral(values[0]) {
+			op = "<="
+		}
+		rounded = []float64{math.Floor(values[0])}
+	case ">", ">=":
+		if !integral(values[0]) {
+			op = ">="
+		}
+		rounded = []float64{math.Ceil(values[0])}
+	case "IN", "NOT IN":
+		for _, v := range values {
+			if integral(v) {
+				rounded = append(rounded, v)
+			}
 		}
+		if op == "IN" && len(rounded) == 0 {
+			return "", nil, fmt.Errorf("no int is in %v", values)
+		}
+	case "BETWEEN", "NOT BETWEEN":
+		rounded = []float64{math.Ceil(values[0]), math.Floor(values[1])}
+	default:
+		rounded = values
+	}
+
+	ints := make([]int, 0, len(rounded))
+	for _, f := range rounded {
+		n, err := toInt(f)
+		if err != nil {
+			return "", nil, err
+		}
+		ints = append(ints, n)
+	}
+	return op, ints, nil
+}
+
+func stringConstraint(c parser.ConditionsIR, values []string) (types.Constraints, error) {
+	op, err := effectiveOp(c)
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case "=":
+		return solver.StringEq{Value: values[0]}, nil
+	case "!=", "<>":
+		return solver.StringNEq{Value: values[0]}, nil
+	case "IN":
+		return solver.StringIn{Values: values}, nil
+	case "NOT IN":
+		return solver.StringNotIn{Values: values}, nil
+	case "LIKE", "ILIKE":
+		return solver.StringLike{Pattern: solver.NewPattern(values[0], op == "ILIKE")}, nil
+	case "NOT LIKE", "NOT ILIKE":
+		return solver.StringNotLike{Pattern: solver.NewPattern(values[0], op == "NOT ILIKE")}, nil
+	default:
+		return nil, fmt.Errorf("bad string op %q", op)
+	}
+}
+
+func MakeConstraint(typ types.Type, c parser.ConditionsIR, operands []parser.LiteralIR) (types.Constraints, error) {
+	if c.Op == "IS NULL" {
+		if c.Negated {
+			return solver.IsNotNull{}, nil
+		}
+		return solver.IsNull{}, nil
+	}
+	if c.Cast != "" {
+		return castConstraint(typ, c, operands)
+	}
+
+	switch typ {
+	case types.IntType:
+		op, ints, err := intOperands(c, operands)
+		if err != nil {
+			return nil, err
+		}
+		return intConstraint(op, ints, "int")
 
 	case types.BoolType:
-		switch c.Right {
-		case "true":
-			return solver.BoolTrue{}, nil
-		case "false":
-			return solver.BoolFalse{}, nil
+		values, err := literals(c, operands, "bool", boolean)
+		if err != nil {
+			return nil, err
+		}
+		value := values[0]
+		switch c.Op {
+		case "bool", "=":
+		case "!=", "<>":
+			value = !value
 		default:
 			return nil, fmt.Errorf("bad bool op %q", c.Op)
 		}
+		if c.Negated {
+			value = !value
+		}
+		if value {
+			return solver.BoolTrue{}, nil
+		}
+		return solver.BoolFalse{}, nil
 
 	case types.TimestampType:
-		n, err := solver.ParseTime(string(c.Right))
+		values, err := literals(c, operands, "timestamp", timestamp)
 		if err != nil {
-			return nil, fmt.Errorf("date/timestamp parse: %w", err)
+			return nil, err
 		}
-		switch c.Op {
-		case "=":
-			return solver.IntEq{Value: n}, nil
-		case "!=":
-			return solver.IntNEq{Value: n}, nil
-		case ">":
-			return solver.IntGt{Value: n}, nil
-		case ">=":
-			return solver.IntGte{Value: n}, nil
-		case "<":
-			return solver.IntLt{Value: n}, nil
-		case "<=":
-			return solver.IntLte{Value: n}, nil
-		default:
-			return nil, fmt.Errorf("bad time op %q", c.Op)
+		op, err := effectiveOp(c)
+		if err != nil {
+			return nil, err
+		}
+		return intConstraint(op, values, "time")
+
+	case types.StringType:
+		values, err := literals(c, operands, "string", str)
+		if err != nil {
+			return nil, err
 		}
+		return stringConstraint(c, values)
+
 	default:
 		return nil, fmt.Errorf("unsupported column type %v", typ)
 	}
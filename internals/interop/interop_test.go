@@ -1,6 +1,8 @@
 package interop_test
 
 import (
+	"fmt"
+	"slices"
 	"testing"
 	"time"
 
@@ -16,10 +18,13 @@ import (
 func TestInterop_FullQueryGeneratorInts(t *testing.T) {
 	seed := int64(42)
 	tests := []struct {
-		name          string
-		query         string
-		table         *table.Table
-		expected      any
+		name     string
+		query    string
+		table    *table.Table
+		expected any
+		// check replaces expected for the rows that are not fixed by the
+		// query
+		check         func(r *require.Assertions, t *table.Table)
 		expectedError error
 	}{
 		{
@@ -52,12 +57,135 @@ func TestInterop_FullQueryGeneratorInts(t *testing.T) {
 					Constraints: nil,
 				},
 			}, 12),
+			check: func(r *require.Assertions, t *table.Table) {
+				r.Len(t.Ints["col_a"], 12)
+				for i, a := range t.Ints["col_a"] {
+					r.True(a > 5 || a == 10 && t.Ints["col_b"][i] == 5, "col_a = %d, col_b = %d", a, t.Ints["col_b"][i])
+				}
+			},
+			expectedError: nil,
+		},
+		{
+			name:  "test with a negated OR",
+			query: "SELECT col_a FROM t WHERE NOT (col_a < 10 OR col_a > 10 OR col_a IS NULL)",
+			table: table.NewTable([]types.Column{
+				{
+					Name:        "col_a",
+					Type:        types.IntType,
+					Constraints: nil,
+				},
+			}, 4),
+			expected: map[string][]int{
+				"col_a": {10, 10, 10, 10},
+			},
+			expectedError: nil,
+		},
+		{
+			name:  "test with negated conditions",
+			query: "SELECT col_a FROM t WHERE NOT col_a < 10 AND NOT (col_a > 10)",
+			table: table.NewTable([]types.Column{
+				{
+					Name:        "col_a",
+					Type:        types.IntType,
+					Constraints: nil,
+				},
+			}, 12),
 			expected: map[string][]int{
 				"col_a": {10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10},
-				"col_b": {5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5},
 			},
 			expectedError: nil,
 		},
+		{
+			name:  "test with in and not in lists",
+			query: "SELECT col_a FROM t WHERE col_a IN (1, 2, 3, 4) AND col_a NOT IN (2, 3)",
+			table: table.NewTable([]types.Column{
+				{
+					Name:        "col_a",
+					Type:        types.IntType,
+					Constraints: nil,
+				},
+			}, 12),
+			expected: map[string][]int{
+				"col_a": {1, 1, 1, 1, 4, 4, 4, 4, 4, 4, 4, 4},
+			},
+			expectedError: nil,
+		},
+		{
+			name:  "test with negative and decimal literals",
+			query: "SELECT col_a FROM t WHERE col_a > -5 AND col_a < -2.5",
+			table: table.NewTable([]types.Column{
+				{
+					Name:        "col_a",
+					Type:        types.IntType,
+					Constraints: nil,
+				},
+			}, 4),
+			expected: map[string][]int{
+				"col_a": {-4, -4, -3, -3},
+			},
+			expectedError: nil,
+		},
+		{
+			name:  "test with scientific notation",
+			query: "SELECT col_a, col_b FROM t WHERE col_a = 1e1 AND col_a != 10.5 AND col_b BETWEEN 8.5 AND 9.99",
+			table: table.NewTable([]types.Column{
+				{
+					Name:        "col_a",
+					Type:        types.IntType,
+					Constraints: nil,
+				},
+				{
+					Name:        "col_b",
+					Type:        types.IntType,
+					Constraints: nil,
+				},
+			}, 4),
+			expected: map[string][]int{
+				"col_a": {10, 10, 10, 10},
+				"col_b": {9, 9, 9, 9},
+			},
+			expectedError: nil,
+		},
+		{
+			name:  "test with arithmetic conditions",
+			query: "SELECT col_a FROM t WHERE col_a + 5 > 20 AND col_a * 2 <= 32",
+			table: table.NewTable([]types.Column{
+				{
+					Name:        "col_a",
+					Type:        types.IntType,
+					Constraints: nil,
+				},
+			}, 4),
+			expected: map[string][]int{
+				"col_a": {16, 16, 16, 16},
+			},
+			expectedError: nil,
+		},
+		{
+			name:  "test with the literal on the left",
+			query: "SELECT col_a FROM t WHERE 10 < col_a AND 11 >= col_a",
+			table: table.NewTable([]types.Column{
+				{
+					Name:        "col_a",
+					Type:        types.IntType,
+					Constraints: nil,
+				},
+			}, 4),
+			expected: map[string][]int{
+				"col_a": {11, 11, 11, 11},
+			},
+			expectedError: nil,
+		},
+		{
+			name:  "test with the largest int as a bound",
+			query: "SELECT col_a FROM t WHERE col_a < 9223372036854775807 AND col_a = 3",
+			table: table.NewTable([]types.Column{
+				{Name: "col_a", Type: types.IntType},
+			}, 2),
+			expected: map[string][]int{
+				"col_a": {3, 3},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -75,12 +203,65 @@ func TestInterop_FullQueryGeneratorInts(t *testing.T) {
 				t.Fatalf("Failed parsing query:\n%s, err:\n%e", tt.query, err)
 			}
 			g.Generate(tt.table, seed)
+			if tt.check != nil {
+				tt.check(r, tt.table)
+				return
+			}
 			tt.table.SortInts()
 			r.Equal(tt.expected, tt.table.Ints)
 		})
 	}
 }
 
+// TestInterop_IntDivision checks that the generated rows meet the
+// original condition, with the division truncated as on ints.
+func TestInterop_IntDivision(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		holds func(a int) bool
+	}{
+		{
+			name:  "test with a quotient above a bound",
+			query: "SELECT a FROM t WHERE a / 4 > 2 AND a < 20",
+			holds: func(a int) bool { return a/4 > 2 && a < 20 },
+		},
+		{
+			name:  "test with a quotient equal to a negative number",
+			query: "SELECT a FROM t WHERE a / 3 = -2",
+			holds: func(a int) bool { return a/3 == -2 },
+		},
+		{
+			name:  "test with a quotient around zero",
+			query: "SELECT a FROM t WHERE NOT a / 5 <= -1 AND (a + 1) / 5 < 1",
+			holds: func(a int) bool { return !(a/5 <= -1) && (a+1)/5 < 1 },
+		},
+		{
+			name:  "test with a negative divisor",
+			query: "SELECT a FROM t WHERE 2 <= a / -3 AND a > -100",
+			holds: func(a int) bool { return 2 <= a/-3 && a > -100 },
+		},
+		{
+			name:  "test with a quotient different from a number",
+			query: "SELECT a FROM t WHERE a / 2 != 0 AND a BETWEEN -3 AND 3",
+			holds: func(a int) bool { return a/2 != 0 && a >= -3 && a <= 3 },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			tbl := newTable("", types.Column{Name: "a", Type: types.IntType})
+			tbl.Dim.Rows = 20
+			generate(t, tt.query, nil, tbl)
+			r.Len(tbl.Ints["a"], 20)
+			for _, a := range tbl.Ints["a"] {
+				r.True(tt.holds(a), "a = %d", a)
+			}
+		})
+	}
+}
+
 func TestInterop_FullQueryGeneratorBool(t *testing.T) {
 	seed := int64(42)
 	tests := []struct {
@@ -105,6 +286,21 @@ func TestInterop_FullQueryGeneratorBool(t *testing.T) {
 			},
 			expectedError: nil,
 		},
+		{
+			name:  "test with one column negated condition",
+			query: "SELECT col_a FROM t WHERE NOT col_a",
+			table: table.NewTable([]types.Column{
+				{
+					Name:        "col_a",
+					Type:        types.BoolType,
+					Constraints: nil,
+				},
+			}, 12),
+			expected: map[string][]bool{
+				"col_a": {false, false, false, false, false, false, false, false, false, false, false, false},
+			},
+			expectedError: nil,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -158,7 +354,7 @@ func TestInterop_FullQueryGeneratorTimestamp(t *testing.T) {
 		},
 		{
 			name:  "test with one column single condition",
-			query: `SELECT col_a FROM t WHERE col_a = "2013-06-17T14:29:00Z"`,
+			query: `SELECT col_a FROM t WHERE col_a = '2013-06-17T14:29:00Z'`,
 			table: table.NewTable([]types.Column{
 				{
 					Name:        "col_a",
@@ -174,6 +370,41 @@ func TestInterop_FullQueryGeneratorTimestamp(t *testing.T) {
 			},
 			expectedError: nil,
 		},
+		{
+			name:  "test with the date on the left",
+			query: `SELECT col_a FROM t WHERE '2013-06-17' = col_a`,
+			table: table.NewTable([]types.Column{
+				{
+					Name:        "col_a",
+					Type:        types.TimestampType,
+					Constraints: nil,
+				},
+			}, 4),
+			expected: map[string][]time.Time{
+				"col_a": {date_1, date_1, date_1, date_1},
+			},
+			expectedError: nil,
+		},
+		{
+			name:  "test with one column between dates",
+			query: `SELECT col_a FROM t WHERE col_a BETWEEN '2013-06-17' AND '2013-06-17T00:00:02Z'`,
+			table: table.NewTable([]types.Column{
+				{
+					Name:        "col_a",
+					Type:        types.TimestampType,
+					Constraints: nil,
+				},
+			}, 4),
+			expected: map[string][]time.Time{
+				"col_a": {
+					solver.FromInt(solver.ToTimestamp("2013-06-17T00:00:00Z")),
+					solver.FromInt(solver.ToTimestamp("2013-06-17T00:00:02Z")),
+					solver.FromInt(solver.ToTimestamp("2013-06-17T00:00:02Z")),
+					solver.FromInt(solver.ToTimestamp("2013-06-17T00:00:02Z")),
+				},
+			},
+			expectedError: nil,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -191,7 +422,1127 @@ func TestInterop_FullQueryGeneratorTimestamp(t *testing.T) {
 				t.Fatalf("Failed parsing query:\n%s, err:\n%e", tt.query, err)
 			}
 			g.Generate(tt.table, seed)
+			tt.table.SortTimestamps()
 			r.Equal(tt.expected, tt.table.Timestamps)
 		})
 	}
 }
+
+func TestInterop_QualifiedColumns(t *testing.T) {
+	amount := types.Column{Name: "amount", Type: types.IntType}
+	tests := []struct {
+		name          string
+		query         string
+		table         *table.Table
+		expected      any
+		expectedError error
+	}{
+		{
+			name:          "test with alias and table name",
+			query:         "SELECT o.amount FROM orders o WHERE o.amount > 5 AND orders.amount < 7",
+			table:         newTable("orders", amount),
+			expected:      map[string][]int{"amount": {6, 6, 6, 6}},
+			expectedError: nil,
+		},
+		{
+			name:          "test with schema-qualified table and unnamed table",
+			query:         "SELECT o.amount FROM sales.orders AS o WHERE o.amount = 3 AND sales.orders.amount >= 3",
+			table:         newTable("", amount),
+			expected:      map[string][]int{"amount": {3, 3, 3, 3}},
+			expectedError: nil,
+		},
+		{
+			name: "test with conditions on a joined table",
+			query: `SELECT o.amount FROM orders o JOIN customers c ON o.cid = c.id
+				WHERE c.age > 5 AND o.amount = 3 AND age < 10`,
+			table:         newTable("orders", amount, types.Column{Name: "cid", Type: types.IntType}),
+			expected:      map[string][]int{"amount": {3, 3, 3, 3}, "cid": {792260, -941749, -345351, -297552}},
+			expectedError: nil,
+		},
+		{
+			name:          "test with quoted identifiers",
+			query:         "SELECT `o`.\"amount\" FROM \"orders\" AS `o` WHERE [o].[amount] = 4",
+			table:         newTable("orders", amount),
+			expected:      map[string][]int{"amount": {4, 4, 4, 4}},
+			expectedError: nil,
+		},
+		{
+			name:          "test with a quoted name holding a dot",
+			query:         `SELECT o."a.b" FROM orders AS o WHERE "a.b" = 6 AND o."a.b" = 6`,
+			table:         newTable("orders", types.Column{Name: "a.b", Type: types.IntType}),
+			expected:      map[string][]int{"a.b": {6, 6, 6, 6}},
+			expectedError: nil,
+		},
+		{
+			name:          "test with unknown alias",
+			query:         "SELECT o.amount FROM orders o WHERE x.amount > 5",
+			table:         newTable("orders", amount),
+			expected:      nil,
+			expectedError: fmt.Errorf(`column x.amount: unknown table or alias "x"`),
+		},
+		{
+			name:          "test with table that is not in the query",
+			query:         "SELECT o.amount FROM orders o WHERE o.amount > 5",
+			table:         newTable("customers", amount),
+			expected:      nil,
+			expectedError: fmt.Errorf(`table "customers" is not in the query`),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if generate(t, tt.query, tt.expectedError, tt.table) {
+				require.Equal(t, tt.expected, tt.table.Ints)
+			}
+		})
+	}
+}
+
+func TestInterop_UnsupportedConditions(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		table         *table.Table
+		expectedError error
+	}{
+		{
+			name:  "test with product of columns",
+			query: "SELECT col_a FROM t WHERE col_a * col_b >= 100",
+			table: newTable("",
+				types.Column{Name: "col_a", Type: types.IntType},
+				types.Column{Name: "col_b", Type: types.IntType},
+			),
+			expectedError: fmt.Errorf("condition col_a * col_b >= 100: product of columns in col_a * col_b is not linear"),
+		},
+		{
+			name:  "test with a column in an IN list",
+			query: "SELECT col_a FROM t WHERE col_a IN (1, col_b)",
+			table: newTable("",
+				types.Column{Name: "col_a", Type: types.IntType},
+				types.Column{Name: "col_b", Type: types.IntType},
+			),
+			expectedError: fmt.Errorf("column col_a: expected number literals, got 1, col_b"),
+		},
+		{
+			name:          "test with a string compared with an int column",
+			query:         "SELECT col_a FROM t WHERE col_a = 'x'",
+			table:         newTable("", types.Column{Name: "col_a", Type: types.IntType}),
+			expectedError: fmt.Errorf("column col_a: expected a number literal, got 'x'"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generate(t, tt.query, tt.expectedError, tt.table)
+		})
+	}
+}
+
+func TestInterop_FullQueryGeneratorNulls(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		table         *table.Table
+		expected      any
+		expectedError error
+	}{
+		{
+			name:  "test with null and not null columns",
+			query: `SELECT deleted_at FROM t WHERE deleted_at IS NULL AND created_at IS NOT NULL`,
+			table: newTable("",
+				types.Column{Name: "deleted_at", Type: types.TimestampType, Nullable: true},
+				types.Column{Name: "created_at", Type: types.TimestampType, Nullable: true},
+			),
+			expected: map[string][]bool{
+				"deleted_at": {true, true, true, true},
+				"created_at": {false, false, false, false},
+			},
+			expectedError: nil,
+		},
+		{
+			name:          "test with is null on a column that is not nullable",
+			query:         `SELECT col_a FROM t WHERE col_a IS NULL`,
+			table:         newTable("", types.Column{Name: "col_a", Type: types.IntType}),
+			expected:      nil,
+			expectedError: fmt.Errorf("column col_a: IS NULL on a column that is not nullable"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if generate(t, tt.query, tt.expectedError, tt.table) {
+				require.Equal(t, tt.expected, tt.table.Nulls)
+			}
+		})
+	}
+}
+
+func TestInterop_FullQueryGeneratorString(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		table         *table.Table
+		expected      any
+		expectedError error
+	}{
+		{
+			name:  "test with one column like condition",
+			query: `SELECT email FROM t WHERE email LIKE '%@corp.com'`,
+			table: newTable("", types.Column{Name: "email", Type: types.StringType}),
+			expected: map[string][]string{
+				"email": {"45z7@corp.com", "9@corp.com", "lde6j@corp.com", "uh@corp.com"},
+			},
+			expectedError: nil,
+		},
+		{
+			name:  "test with a prefix and a suffix from two like conditions",
+			query: `SELECT email FROM t WHERE email LIKE 'a%' AND email LIKE '%z'`,
+			table: newTable("", types.Column{Name: "email", Type: types.StringType}),
+			expected: map[string][]string{
+				"email": {"a45z7z", "a9z", "alde6jz", "auhz"},
+			},
+			expectedError: nil,
+		},
+		{
+			name:  "test with two columns in list and like conditions",
+			query: `SELECT email FROM t WHERE email ILIKE 'ADMIN@CORP.COM' AND country IN ('SE', 'NO') AND country <> 'NO'`,
+			table: newTable("",
+				types.Column{Name: "email", Type: types.StringType},
+				types.Column{Name: "country", Type: types.StringType},
+			),
+			expected: map[string][]string{
+				"email":   {"ADMIN@CORP.COM", "ADMIN@CORP.COM", "ADMIN@CORP.COM", "ADMIN@CORP.COM"},
+				"country": {"SE", "SE", "SE", "SE"},
+			},
+			expectedError: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if generate(t, tt.query, tt.expectedError, tt.table) {
+				tt.table.SortStrings()
+				require.Equal(t, tt.expected, tt.table.Strings)
+			}
+		})
+	}
+}
+
+func TestInterop_NestedQueries(t *testing.T) {
+	amount := types.Column{Name: "amount", Type: types.IntType}
+	tests := []struct {
+		name          string
+		query         string
+		table         *table.Table
+		expected      any
+		expectedError error
+	}{
+		{
+			name: "test with filters in the CTE and the outer query",
+			query: `WITH big AS (SELECT amount FROM orders WHERE amount > 5)
+				SELECT amount FROM big WHERE amount < 7`,
+			table:         newTable("", amount),
+			expected:      map[string][]int{"amount": {6, 6, 6, 6}},
+			expectedError: nil,
+		},
+		{
+			name: "test with a chain of CTEs and renamed columns",
+			query: `WITH big (total) AS (SELECT o.amount FROM sales.orders o WHERE o.amount >= 3),
+				small AS (SELECT * FROM big WHERE total <= 3)
+				SELECT s.total AS t FROM small s WHERE s.total != 4`,
+			table:         newTable("orders", amount),
+			expected:      map[string][]int{"amount": {3, 3, 3, 3}},
+			expectedError: nil,
+		},
+		{
+			name: "test with an aliased column",
+			query: `WITH big AS (SELECT amount AS total FROM orders WHERE amount > 1)
+				SELECT total FROM big WHERE total = 2`,
+			table:         newTable("orders", amount),
+			expected:      map[string][]int{"amount": {2, 2, 2, 2}},
+			expectedError: nil,
+		},
+		{
+			name: "test with a subquery in FROM",
+			query: `SELECT s.total FROM (SELECT o.amount AS total FROM orders o WHERE o.amount > 5) AS s
+				WHERE s.total < 7`,
+			table:         newTable("", amount),
+			expected:      map[string][]int{"amount": {6, 6, 6, 6}},
+			expectedError: nil,
+		},
+		{
+			name: "test with a subquery in JOIN over a CTE",
+			query: `WITH big AS (SELECT amount FROM orders WHERE amount >= 8)
+				SELECT c.id FROM customers c
+				JOIN (SELECT * FROM big WHERE amount <= 8) b ON b.amount = c.id`,
+			table:         newTable("orders", amount),
+			expected:      map[string][]int{"amount": {8, 8, 8, 8}},
+			expectedError: nil,
+		},
+		{
+			name:          "test with a subquery in FROM without an alias",
+			query:         `SELECT amount FROM (SELECT amount FROM orders WHERE amount > 5) WHERE amount < 7`,
+			table:         newTable("", amount),
+			expected:      nil,
+			expectedError: fmt.Errorf("a subquery in FROM or JOIN needs an alias"),
+		},
+		{
+			name: "test with a computed column",
+			query: `WITH big AS (SELECT amount + 1 AS total FROM orders)
+				SELECT total FROM big WHERE total = 2`,
+			table:         newTable("orders", amount),
+			expected:      nil,
+			expectedError: fmt.Errorf("column total: column total of big is computed"),
+		},
+		{
+			name: "test with a recursive CTE",
+			query: `WITH RECURSIVE big AS (SELECT amount FROM orders WHERE amount = 1)
+				SELECT amount FROM big`,
+			table:         newTable("orders", amount),
+			expected:      nil,
+			expectedError: fmt.Errorf(`recursive CTE "big" is not supported`),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if generate(t, tt.query, tt.expectedError, tt.table) {
+				require.Equal(t, tt.expected, tt.table.Ints)
+			}
+		})
+	}
+}
+
+func TestInterop_SemiJoins(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		anti          bool
+		distinct      bool
+		expectedError error
+	}{
+		{
+			name: "test with EXISTS",
+			query: `SELECT c.id FROM customers c WHERE c.id > 10 AND EXISTS (
+				SELECT 1 FROM orders o WHERE o.customer_id = c.id AND o.amount > 100)`,
+		},
+		{
+			name: "test with NOT EXISTS",
+			query: `SELECT c.id FROM customers c WHERE NOT EXISTS (
+				SELECT 1 FROM orders o WHERE c.id = o.customer_id AND o.amount > 100)`,
+			anti: true,
+		},
+		{
+			name:  "test with IN over a subquery",
+			query: "SELECT id FROM customers WHERE id IN (SELECT customer_id FROM orders WHERE amount > 100)",
+		},
+		{
+			name:  "test with NOT IN over a subquery",
+			query: "SELECT id FROM customers WHERE id NOT IN (SELECT customer_id FROM orders WHERE amount > 100)",
+			anti:  true,
+		},
+		{
+			name:     "test with IN over a subquery sorted by the key",
+			query:    "SELECT id FROM customers WHERE id IN (SELECT customer_id FROM orders WHERE amount > 100) ORDER BY id LIMIT 3",
+			distinct: true,
+		},
+		{
+			name:          "test with uncorrelated NOT EXISTS",
+			query:         "SELECT id FROM customers WHERE NOT EXISTS (SELECT 1 FROM orders WHERE amount > 100)",
+			expectedError: fmt.Errorf("NOT EXISTS without a correlated key is not supported"),
+		},
+		{
+			name: "test with correlation on another operator",
+			query: `SELECT c.id FROM customers c WHERE EXISTS (
+				SELECT 1 FROM orders o WHERE o.customer_id > c.id)`,
+			expectedError: fmt.Errorf("EXISTS: condition o.customer_id > c.id: subqueries are only correlated on ="),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			outer := newTable("customers", types.Column{Name: "id", Type: types.IntType})
+			inner := newTable("orders",
+				types.Column{Name: "customer_id", Type: types.IntType},
+				types.Column{Name: "amount", Type: types.IntType},
+			)
+			if !generate(t, tt.query, tt.expectedError, outer, inner) {
+				return
+			}
+			for _, amount := range inner.Ints["amount"] {
+				r.Greater(amount, 100)
+			}
+			for _, id := range outer.Ints["id"] {
+				if tt.anti {
+					r.NotContains(inner.Ints["customer_id"], id)
+				} else {
+					r.Contains(inner.Ints["customer_id"], id)
+				}
+			}
+			if tt.distinct {
+				ids := slices.Clone(outer.Ints["id"])
+				slices.Sort(ids)
+				r.Equal(len(ids), len(slices.Compact(ids)))
+			}
+		})
+	}
+}
+
+func TestInterop_ImplicitJoins(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		expectedError error
+	}{
+		{
+			name: "test with the join predicate first",
+			query: `SELECT o.amount FROM orders o, customers c
+				WHERE o.cid = c.id AND o.amount > 5 AND c.age < 30`,
+		},
+		{
+			name: "test with the join predicate reversed",
+			query: `SELECT o.amount FROM orders o, customers c
+				WHERE c.age < 30 AND c.id = o.cid AND o.amount > 5`,
+		},
+		{
+			name: "test with a filter on the key",
+			query: `SELECT o.amount FROM orders o, customers c
+				WHERE o.cid = c.id AND c.id > 10 AND o.amount > 5 AND c.age < 30`,
+		},
+		{
+			name:          "test with a join on another operator",
+			query:         "SELECT o.amount FROM orders o, customers c WHERE o.cid < c.id",
+			expectedError: fmt.Errorf("condition o.cid < c.id: tables are only joined on ="),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			o := newTable("orders",
+				types.Column{Name: "cid", Type: types.IntType},
+				types.Column{Name: "amount", Type: types.IntType},
+			)
+			c := newTable("customers",
+				types.Column{Name: "id", Type: types.IntType},
+				types.Column{Name: "age", Type: types.IntType},
+			)
+			if !generate(t, tt.query, tt.expectedError, o, c) {
+				return
+			}
+			for _, amount := range o.Ints["amount"] {
+				r.Greater(amount, 5)
+			}
+			for _, age := range c.Ints["age"] {
+				r.Less(age, 30)
+			}
+			for _, cid := range o.Ints["cid"] {
+				r.Contains(c.Ints["id"], cid)
+			}
+		})
+	}
+}
+
+func TestInterop_ExplicitJoins(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		expectedError error
+	}{
+		{
+			name: "test with an ON clause",
+			query: `SELECT o.amount FROM orders o JOIN customers c ON o.cid = c.cid AND c.age < 30
+				WHERE o.amount > 5`,
+		},
+		{
+			name:  "test with a reversed ON clause on a LEFT JOIN",
+			query: "SELECT o.amount FROM orders o LEFT JOIN customers c ON c.cid = o.cid WHERE o.amount > 5 AND c.age < 30",
+		},
+		{
+			name:  "test with a USING clause",
+			query: "SELECT amount FROM orders JOIN customers USING (cid) WHERE amount > 5 AND age < 30",
+		},
+		{
+			name:          "test with a join on another operator",
+			query:         "SELECT o.amount FROM orders o JOIN customers c ON o.cid > c.cid",
+			expectedError: fmt.Errorf("condition o.cid > c.cid: tables are only joined on ="),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			o := newTable("orders",
+				types.Column{Name: "cid", Type: types.IntType},
+				types.Column{Name: "amount", Type: types.IntType},
+			)
+			c := newTable("customers",
+				types.Column{Name: "age", Type: types.IntType},
+				types.Column{Name: "cid", Type: types.IntType},
+			)
+			if !generate(t, tt.query, tt.expectedError, o, c) {
+				return
+			}
+			for _, amount := range o.Ints["amount"] {
+				r.Greater(amount, 5)
+			}
+			for _, age := range c.Ints["age"] {
+				r.Less(age, 30)
+			}
+			for _, cid := range o.Ints["cid"] {
+				r.Contains(c.Ints["cid"], cid)
+			}
+		})
+	}
+}
+
+func TestInterop_SetOperations(t *testing.T) {
+	amount := types.Column{Name: "amount", Type: types.IntType}
+	tests := []struct {
+		name          string
+		query         string
+		table         *table.Table
+		expected      any
+		expectedError error
+	}{
+		{
+			name: "test with UNION ALL",
+			query: `SELECT amount FROM orders WHERE amount = 1
+				UNION ALL SELECT amount FROM orders WHERE amount = 2`,
+			table:         newTable("", amount),
+			expected:      map[string][]int{"amount": {1, 1, 2, 2}},
+			expectedError: nil,
+		},
+		{
+			name: "test with UNION over another table",
+			query: `SELECT amount FROM orders WHERE amount = 1
+				UNION SELECT total FROM refunds WHERE total = 2`,
+			table:         newTable("orders", amount),
+			expected:      map[string][]int{"amount": {1, 1, 1, 1}},
+			expectedError: nil,
+		},
+		{
+			name: "test with INTERSECT",
+			query: `SELECT amount FROM orders WHERE amount > 1
+				INTERSECT SELECT amount FROM orders WHERE amount < 3`,
+			table:         newTable("orders", amount),
+			expected:      map[string][]int{"amount": {2, 2, 2, 2}},
+			expectedError: nil,
+		},
+		{
+			name: "test with EXCEPT",
+			query: `SELECT amount FROM orders WHERE amount BETWEEN 1 AND 2
+				EXCEPT SELECT amount FROM orders WHERE amount = 1`,
+			table:         newTable("orders", amount),
+			expected:      map[string][]int{"amount": {2, 2, 2, 2}},
+			expectedError: nil,
+		},
+		{
+			name: "test with EXCEPT over an arm with an OR",
+			query: `SELECT amount FROM orders WHERE amount BETWEEN 1 AND 3
+				EXCEPT SELECT amount FROM orders WHERE amount = 2 OR amount = 3`,
+			table:         newTable("orders", amount),
+			expected:      map[string][]int{"amount": {1, 1, 1, 1}},
+			expectedError: nil,
+		},
+		{
+			name: "test with EXCEPT over an arm without conditions",
+			query: `SELECT amount FROM orders WHERE amount = 1
+				EXCEPT SELECT amount FROM orders`,
+			table:         newTable("orders", amount),
+			expected:      nil,
+			expectedError: fmt.Errorf("EXCEPT over an arm without conditions leaves no rows"),
+		},
+		{
+			name: "test with UNION in a CTE",
+			query: `WITH u AS (SELECT amount FROM orders UNION SELECT amount FROM refunds)
+				SELECT amount FROM u`,
+			table:         newTable("orders", amount),
+			expected:      nil,
+			expectedError: fmt.Errorf("CTE u: UNION is only supported in the outer query"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if generate(t, tt.query, tt.expectedError, tt.table) {
+				tt.table.SortInts()
+				require.Equal(t, tt.expected, tt.table.Ints)
+			}
+		})
+	}
+}
+
+func TestInterop_GroupBy(t *testing.T) {
+	sales := func() *table.Table {
+		return newTable("sales",
+			types.Column{Name: "region", Type: types.StringType},
+			types.Column{Name: "amount", Type: types.IntType},
+		)
+	}
+	// groups collects the amounts of every region
+	groups := func(t *table.Table) map[string][]int {
+		out := map[string][]int{}
+		for i, region := range t.Strings["region"] {
+			out[region] = append(out[region], t.Ints["amount"][i])
+		}
+		return out
+	}
+	tests := []struct {
+		name          string
+		query         string
+		check         func(r *require.Assertions, t *table.Table)
+		expectedError error
+	}{
+		{
+			name: "test with COUNT and SUM",
+			query: `SELECT region, SUM(amount) FROM sales WHERE region IN ('eu', 'us', 'apac')
+				GROUP BY region HAVING COUNT(*) >= 2 AND SUM(amount) > 100`,
+			check: func(r *require.Assertions, t *table.Table) {
+				g := groups(t)
+				r.Len(g, 2)
+				for region, amounts := range g {
+					r.Contains([]string{"eu", "us", "apac"}, region)
+					r.GreaterOrEqual(len(amounts), 2)
+					sum := 0
+					for _, a := range amounts {
+						sum += a
+					}
+					r.Greater(sum, 100)
+				}
+			},
+		},
+		{
+			name:  "test with groups larger than half the rows",
+			query: "SELECT region FROM sales GROUP BY 1 HAVING 3 <= COUNT(*)",
+			check: func(r *require.Assertions, t *table.Table) {
+				r.Equal(6, t.Dim.Rows)
+				g := groups(t)
+				r.Len(g, 2)
+				for _, amounts := range g {
+					r.Len(amounts, 3)
+				}
+			},
+		},
+		{
+			name:  "test with MIN and MAX",
+			query: "SELECT region FROM sales GROUP BY region HAVING MIN(amount) >= 5 AND NOT MAX(amount) >= 7",
+			check: func(r *require.Assertions, t *table.Table) {
+				for _, amounts := range groups(t) {
+					for _, a := range amounts {
+						r.GreaterOrEqual(a, 5)
+						r.Less(a, 7)
+					}
+				}
+			},
+		},
+		{
+			name:  "test with a SUM the size of the groups does not divide",
+			query: "SELECT region FROM sales GROUP BY region HAVING COUNT(*) = 4 AND SUM(amount) = 7 LIMIT 2",
+			check: func(r *require.Assertions, t *table.Table) {
+				g := groups(t)
+				r.Len(g, 2)
+				for _, amounts := range g {
+					r.Len(amounts, 4)
+					sum := 0
+					for _, a := range amounts {
+						sum += a
+					}
+					r.Equal(7, sum)
+				}
+			},
+		},
+		{
+			name:  "test with a negative SUM over the whole table",
+			query: "SELECT SUM(amount) FROM sales HAVING SUM(amount) = -10",
+			check: func(r *require.Assertions, t *table.Table) {
+				sum := 0
+				for _, a := range t.Ints["amount"] {
+					sum += a
+				}
+				r.Equal(-10, sum)
+			},
+		},
+		{
+			name:  "test with an aggregate over the whole table",
+			query: "SELECT COUNT(*) FROM sales HAVING COUNT(*) = 5",
+			check: func(r *require.Assertions, t *table.Table) {
+				r.Len(t.Ints["amount"], 5)
+			},
+		},
+		{
+			name:          "test with COUNT conditions that can not hold together",
+			query:         "SELECT region FROM sales GROUP BY region HAVING COUNT(*) > 5 AND COUNT(*) < 3",
+			expectedError: fmt.Errorf("the COUNT conditions leave no possible group size"),
+		},
+		{
+			name:          "test with GROUP BY in a compound query",
+			query:         "SELECT region FROM sales GROUP BY region UNION SELECT region FROM sales WHERE amount > 1",
+			expectedError: fmt.Errorf("GROUP BY over several branches of rows, as of a compound query or a CASE, is not supported"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tbl := sales()
+			if generate(t, tt.query, tt.expectedError, tbl) {
+				tt.check(require.New(t), tbl)
+			}
+		})
+	}
+}
+
+func TestInterop_OrderByLimit(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		wantRows     int
+		wantDistinct bool
+		wantValues   int // number of different amounts
+	}{
+		{
+			name:         "test with LIMIT and OFFSET",
+			query:        "SELECT amount FROM orders WHERE amount BETWEEN 1 AND 100 ORDER BY amount DESC LIMIT 5 OFFSET 3",
+			wantRows:     8,
+			wantDistinct: true,
+			wantValues:   8,
+		},
+		{
+			name:         "test with a LIMIT below the number of rows",
+			query:        "SELECT amount FROM orders ORDER BY 1 LIMIT 2",
+			wantRows:     4,
+			wantDistinct: true,
+			wantValues:   4,
+		},
+		{
+			name:         "test with an ordering alias",
+			query:        "SELECT o.amount AS a FROM orders o WHERE o.amount IN (1, 2, 3, 4) ORDER BY a",
+			wantRows:     4,
+			wantDistinct: true,
+			wantValues:   4,
+		},
+		{
+			name: "test with a compound query",
+			query: `SELECT amount FROM orders WHERE amount < 0
+				UNION ALL SELECT amount FROM orders WHERE amount > 0 ORDER BY amount LIMIT 10`,
+			wantRows:     10,
+			wantDistinct: true,
+			wantValues:   10,
+		},
+		{
+			name:       "test with too few values for a distinct key",
+			query:      "SELECT amount FROM orders WHERE amount = 1 ORDER BY amount LIMIT 3",
+			wantRows:   4,
+			wantValues: 1,
+		},
+		{
+			name:       "test with a grouped query",
+			query:      "SELECT amount, COUNT(*) FROM orders GROUP BY amount ORDER BY 2 DESC LIMIT 3",
+			wantRows:   6,
+			wantValues: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			tbl := newTable("orders", types.Column{Name: "amount", Type: types.IntType})
+			generate(t, tt.query, nil, tbl)
+			r.Equal(tt.wantRows, tbl.Dim.Rows)
+			r.Equal(tt.wantDistinct, tbl.Schema[0].Distinct)
+			r.Len(tbl.Ints["amount"], tt.wantRows)
+			values := map[int]bool{}
+			for _, a := range tbl.Ints["amount"] {
+				values[a] = true
+			}
+			r.Len(values, tt.wantValues)
+		})
+	}
+}
+
+func TestInterop_CaseExpressions(t *testing.T) {
+	customers := func() *table.Table {
+		return newTable("customers",
+			types.Column{Name: "tier", Type: types.StringType},
+			types.Column{Name: "credit_limit", Type: types.IntType},
+		)
+	}
+	tests := []struct {
+		name          string
+		query         string
+		check         func(r *require.Assertions, t *table.Table)
+		expectedError error
+	}{
+		{
+			name:  "test with a single reachable branch",
+			query: "SELECT tier FROM customers WHERE CASE WHEN tier = 'gold' THEN credit_limit ELSE 0 END > 100",
+			check: func(r *require.Assertions, t *table.Table) {
+				r.Nil(t.Branches)
+				for i, tier := range t.Strings["tier"] {
+					r.Equal("gold", tier)
+					r.Greater(t.Ints["credit_limit"][i], 100)
+				}
+			},
+		},
+		{
+			name: "test with every branch reachable",
+			query: `SELECT tier FROM customers
+				WHERE CASE WHEN tier = 'gold' THEN credit_limit ELSE credit_limit * 2 END > 100`,
+			check: func(r *require.Assertions, t *table.Table) {
+				gold := 0
+				for i, tier := range t.Strings["tier"] {
+					if tier == "gold" {
+						gold++
+						r.Greater(t.Ints["credit_limit"][i], 100)
+					} else {
+						r.Greater(t.Ints["credit_limit"][i]*2, 100)
+					}
+				}
+				r.Equal(2, gold)
+			},
+		},
+		{
+			name:  "test with a simple CASE",
+			query: "SELECT tier FROM customers WHERE 2 >= CASE tier WHEN 'gold' THEN 1 WHEN 'silver' THEN 2 ELSE 3 END",
+			check: func(r *require.Assertions, t *table.Table) {
+				tiers := slices.Clone(t.Strings["tier"])
+				slices.Sort(tiers)
+				r.Equal([]string{"gold", "gold", "silver", "silver"}, tiers)
+			},
+		},
+		{
+			name: "test with a branch the other conditions rule out",
+			query: `SELECT tier FROM customers WHERE tier = 'gold'
+				AND CASE WHEN tier = 'gold' THEN credit_limit ELSE credit_limit * 2 END > 100`,
+			check: func(r *require.Assertions, t *table.Table) {
+				r.Nil(t.Branches)
+				for i, tier := range t.Strings["tier"] {
+					r.Equal("gold", tier)
+					r.Greater(t.Ints["credit_limit"][i], 100)
+				}
+			},
+		},
+		{
+			name: "test with an OR in an earlier WHEN",
+			query: `SELECT tier FROM customers WHERE tier IN ('gold', 'silver')
+				AND CASE WHEN credit_limit > 100 OR tier = 'gold' THEN 1 ELSE 2 END = 2`,
+			check: func(r *require.Assertions, t *table.Table) {
+				r.Len(t.Strings["tier"], 4)
+				for i, tier := range t.Strings["tier"] {
+					r.Equal("silver", tier)
+					r.LessOrEqual(t.Ints["credit_limit"][i], 100)
+				}
+			},
+		},
+		{
+			name: "test with an AND in an earlier WHEN",
+			query: `SELECT tier FROM customers WHERE tier IN ('gold', 'silver')
+				AND CASE WHEN credit_limit > 100 AND tier = 'gold' THEN 1 ELSE 2 END = 2`,
+			check: func(r *require.Assertions, t *table.Table) {
+				r.Len(t.Strings["tier"], 4)
+				for i, tier := range t.Strings["tier"] {
+					r.False(tier == "gold" && t.Ints["credit_limit"][i] > 100)
+				}
+			},
+		},
+		{
+			name:          "test with no branch passing",
+			query:         "SELECT tier FROM customers WHERE CASE WHEN tier = 'gold' THEN 1 ELSE 2 END > 5",
+			expectedError: fmt.Errorf("CASE: no branch of the CASE passes the comparison"),
+		},
+		{
+			name: "test with no branch reachable",
+			query: `SELECT tier FROM customers WHERE credit_limit < 10
+				AND CASE WHEN tier = 'gold' THEN credit_limit ELSE credit_limit * 2 END > 100`,
+			expectedError: fmt.Errorf("no branch of the CASE can be reached"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tbl := customers()
+			if generate(t, tt.query, tt.expectedError, tbl) {
+				tt.check(require.New(t), tbl)
+			}
+		})
+	}
+}
+
+func TestInterop_Casts(t *testing.T) {
+	events := func() *table.Table {
+		t := newTable("events",
+			types.Column{Name: "ts", Type: types.TimestampType},
+			types.Column{Name: "amount", Type: types.IntType},
+		)
+		t.Dim.Rows = 8
+		return t
+	}
+	day := func(date string) (time.Time, time.Time) {
+		first := solver.FromInt(solver.ToDate(date))
+		return first, first.Add(24*time.Hour - time.Second)
+	}
+	tests := []struct {
+		name          string
+		dialect       *parser.Dialect
+		query         string
+		check         func(r *require.Assertions, t *table.Table)
+		expectedError error
+	}{
+		{
+			name:  "test with a timestamp cast to the date it is on",
+			query: "SELECT ts FROM events WHERE CAST(ts AS DATE) = '2024-01-01'",
+			check: func(r *require.Assertions, t *table.Table) {
+				first, last := day("2024-01-01")
+				for _, ts := range t.Timestamps["ts"] {
+					r.False(ts.Before(first))
+					r.False(ts.After(last))
+				}
+			},
+		},
+		{
+			name:  "test with a timestamp cast to dates after a day",
+			query: "SELECT ts FROM events WHERE ts::date > '2024-01-01' AND ts::date <= '2024-01-02'",
+			check: func(r *require.Assertions, t *table.Table) {
+				first, last := day("2024-01-02")
+				for _, ts := range t.Timestamps["ts"] {
+					r.False(ts.Before(first))
+					r.False(ts.After(last))
+				}
+			},
+		},
+		{
+			name:  "test with a numeric cast",
+			query: "SELECT ts FROM events WHERE TRY_CAST(amount AS DECIMAL(10, 2)) BETWEEN 1.5 AND 3",
+			check: func(r *require.Assertions, t *table.Table) {
+				for _, a := range t.Ints["amount"] {
+					r.Contains([]int{2, 3}, a)
+				}
+			},
+		},
+		{
+			name:    "test with the numeric types of BigQuery",
+			dialect: parser.BigQuery,
+			query:   "SELECT ts FROM events WHERE SAFE_CAST(amount AS INT64) >= 2 AND CAST(amount AS FLOAT64) < 4",
+			check: func(r *require.Assertions, t *table.Table) {
+				for _, a := range t.Ints["amount"] {
+					r.Contains([]int{2, 3}, a)
+				}
+			},
+		},
+		{
+			name:  "test with a DATE function",
+			query: "SELECT ts FROM events WHERE '2024-01-01' = date(events.ts)",
+			check: func(r *require.Assertions, t *table.Table) {
+				first, last := day("2024-01-01")
+				for _, ts := range t.Timestamps["ts"] {
+					r.False(ts.Before(first))
+					r.False(ts.After(last))
+				}
+			},
+		},
+		{
+			name:          "test with a function that is not supported",
+			query:         "SELECT ts FROM events WHERE coalesce(amount, 0) > 5",
+			expectedError: fmt.Errorf("condition COALESCE(amount, 0) > 5: function COALESCE is not supported"),
+		},
+		{
+			name:          "test with a cast to another type",
+			query:         "SELECT ts FROM events WHERE CAST(amount AS STRING) = '5'",
+			expectedError: fmt.Errorf("column amount: CAST of a int column to string is not supported"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dialect := tt.dialect
+			if dialect == nil {
+				dialect = parser.Generic
+			}
+			tbl := events()
+			if generateIn(t, dialect, tt.query, tt.expectedError, tbl) {
+				tt.check(require.New(t), tbl)
+			}
+		})
+	}
+}
+
+func TestInterop_WindowFunctions(t *testing.T) {
+	events := func() *table.Table {
+		return newTable("events",
+			types.Column{Name: "id", Type: types.IntType},
+			types.Column{Name: "ts", Type: types.TimestampType},
+		)
+	}
+	// partitions counts the rows of every id, and checks that no two rows
+	// share a ts
+	partitions := func(r *require.Assertions, t *table.Table) map[int]int {
+		out := map[int]int{}
+		seen := map[time.Time]bool{}
+		for i, id := range t.Ints["id"] {
+			out[id]++
+			ts := t.Timestamps["ts"][i]
+			r.False(seen[ts])
+			seen[ts] = true
+		}
+		return out
+	}
+	tests := []struct {
+		name          string
+		query         string
+		check         func(r *require.Assertions, t *table.Table)
+		expectedError error
+	}{
+		{
+			name: "test with the latest row of every partition",
+			query: `SELECT id, ts FROM events WHERE id BETWEEN 1 AND 10
+				QUALIFY row_number() OVER (PARTITION BY id ORDER BY ts DESC) = 1`,
+			check: func(r *require.Assertions, t *table.Table) {
+				p := partitions(r, t)
+				r.Len(p, 2)
+				for id, rows := range p {
+					r.GreaterOrEqual(id, 1)
+					r.LessOrEqual(id, 10)
+					r.Equal(2, rows)
+				}
+			},
+		},
+		{
+			name:  "test with partitions larger than half the rows",
+			query: "SELECT id FROM events QUALIFY RANK() OVER (PARTITION BY events.id ORDER BY ts) <= 2",
+			check: func(r *require.Assertions, t *table.Table) {
+				r.Equal(6, t.Dim.Rows)
+				p := partitions(r, t)
+				r.Len(p, 2)
+				for _, rows := range p {
+					r.Equal(3, rows)
+				}
+			},
+		},
+		{
+			name:  "test with a window over the whole table",
+			query: "SELECT id FROM events QUALIFY row_number() OVER (ORDER BY ts) <= 5",
+			check: func(r *require.Assertions, t *table.Table) {
+				r.Equal(6, t.Dim.Rows)
+				r.Len(t.Timestamps["ts"], 6)
+				partitions(r, t)
+			},
+		},
+		{
+			name:  "test with too few values for a distinct order",
+			query: "SELECT id FROM events WHERE id = 1 QUALIFY row_number() OVER (ORDER BY id) <= 5",
+			check: func(r *require.Assertions, t *table.Table) {
+				r.Equal(6, t.Dim.Rows)
+				r.Equal([]int{1, 1, 1, 1, 1, 1}, t.Ints["id"])
+			},
+		},
+		{
+			name:          "test with a rank no row has",
+			query:         "SELECT id FROM events QUALIFY row_number() OVER (PARTITION BY id ORDER BY ts) < 1",
+			expectedError: fmt.Errorf("the ROW_NUMBER condition leaves no rows"),
+		},
+		{
+			name:          "test with a window function that is not supported",
+			query:         "SELECT id FROM events QUALIFY sum(id) OVER (PARTITION BY id) > 5",
+			expectedError: fmt.Errorf("window function SUM is not supported"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tbl := events()
+			if generate(t, tt.query, tt.expectedError, tbl) {
+				tt.check(require.New(t), tbl)
+			}
+		})
+	}
+}
+
+func TestInterop_Diagnostics(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		expectedError error
+		expected      string
+	}{
+		{
+			name:          "test with a misspelled column",
+			query:         "SELECT o.amount FROM orders o\nWHERE o.id > 1 AND o.amout > 5",
+			expectedError: fmt.Errorf(`unknown column "o.amout"`),
+			expected:      "q.sql:2:20: unknown column \"o.amout\"\n  WHERE o.id > 1 AND o.amout > 5\n                     ^\n  did you mean \"amount\"?",
+		},
+		{
+			name: "test with a misspelled alias in a subquery",
+			query: "SELECT o.amount FROM orders o WHERE EXISTS (\n" +
+				"  SELECT 1 FROM orders p WHERE p.id = o.id AND pp.amount > 1)",
+			expectedError: fmt.Errorf(`EXISTS: column pp.amount: unknown table or alias "pp"`),
+			expected: "q.sql:2:48: EXISTS: column pp.amount: unknown table or alias \"pp\"\n" +
+				"    SELECT 1 FROM orders p WHERE p.id = o.id AND pp.amount > 1)\n" +
+				"                                                 ^\n" +
+				"  did you mean \"p\"?",
+		},
+		{
+			name:          "test with IS NULL on a column that is not nullable",
+			query:         "SELECT amount FROM orders WHERE amount IS NULL",
+			expectedError: fmt.Errorf("column amount: IS NULL on a column that is not nullable"),
+			expected: "q.sql:1:33: column amount: IS NULL on a column that is not nullable\n" +
+				"  SELECT amount FROM orders WHERE amount IS NULL\n" +
+				"                                  ^",
+		},
+		{
+			name:          "test with an error on no predicate",
+			query:         "SELECT amount FROM orders GROUP BY amount QUALIFY ROW_NUMBER() OVER (ORDER BY id) = 1",
+			expectedError: fmt.Errorf("window functions in a grouped query are not supported"),
+			expected:      "window functions in a grouped query are not supported",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			q, err := parser.Parser.ParseString("q.sql", tt.query)
+			if err != nil {
+				t.Fatalf("Failed parsing query:\n%s, err:\n%e", tt.query, err)
+			}
+
+			interopQuery := interop.Wrap(q)
+			err = interopQuery.AddConditions(newTable("orders",
+				types.Column{Name: "amount", Type: types.IntType},
+				types.Column{Name: "id", Type: types.IntType},
+			))
+			r.EqualError(err, tt.expectedError.Error())
+			r.Equal(tt.expected, parser.Diagnose(tt.query, err).Report())
+		})
+	}
+}
+
+// newTable returns a table of four rows by the given name, which is
+// left empty for a table that stands for the one the query reads.
+func newTable(name string, cols ...types.Column) *table.Table {
+	t := table.NewTable(cols, 4)
+	t.Name = name
+	return t
+}
+
+// generate adds the conditions of a query to the tables in turn, and
+// fills them with rows. When wantErr is set, adding the conditions has
+// to fail with it, and the tables are left empty. It reports whether
+// the tables were filled.
+func generate(t *testing.T, query string, wantErr error, tables ...*table.Table) bool {
+	t.Helper()
+	return generateIn(t, parser.Generic, query, wantErr, tables...)
+}
+
+// generateIn is generate for a query written in the given dialect.
+func generateIn(t *testing.T, dialect *parser.Dialect, query string, wantErr error, tables ...*table.Table) bool {
+	t.Helper()
+	r := require.New(t)
+
+	q, err := dialect.ParseString("", query)
+	r.NoError(err, "parsing query:\n%s", query)
+
+	interopQuery := interop.Wrap(q)
+	for _, tbl := range tables {
+		err := interopQuery.AddConditions(tbl)
+		if wantErr != nil {
+			r.EqualError(err, wantErr.Error())
+			return false
+		}
+		r.NoError(err)
+	}
+	var g solver.Generator
+	for _, tbl := range tables {
+		g.Generate(tbl, 42)
+	}
+	return true
+}
@@ -0,0 +1,111 @@
+package interop
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/phdah/sql-tdg/internals/parser"
+	"github.com/phdah/sql-tdg/internals/solver"
+	"github.com/phdah/sql-tdg/internals/types"
+)
+
+// orderColumns resolves a key of an ORDER BY clause onto the columns it
+// sorts by. A key in the select list sorts the column in that place of
+// every arm of a compound query.
+func orderColumns(key parser.OrderIR, arms []*parser.SelectCore, scopes []*scope) ([]columnRef, error) {
+	pos := key.Position - 1
+	if key.Position == 0 {
+		for i, item := range arms[0].Select.Items {
+			col, ok := item.Column()
+			if ok && col == *key.Column || key.Column.Table == "" && strings.EqualFold(item.Name(), key.Column.Name) {
+				pos = i
+				break
+			}
+		}
+		if pos < 0 {
+			// Compound queries can only be sorted by the columns they select
+			if len(arms) > 1 {
+				return nil, nil
+			}
+			ref, err := scopes[0].resolve(*key.Column)
+			if err != nil {
+				return nil, fmt.Errorf("ORDER BY %s: %w", key.Column, err)
+			}
+			return []columnRef{ref}, nil
+		}
+	}
+	if pos >= len(arms[0].Select.Items) {
+		return nil, fmt.Errorf("ORDER BY %d is not a position in the select list", key.Position)
+	}
+
+	var refs []columnRef
+	for i, arm := range arms {
+		if pos >= len(arm.Select.Items) {
+			continue
+		}
+		col, ok := arm.Select.Items[pos].Column()
+		if !ok {
+			continue
+		}
+		ref, err := scopes[i].resolve(col)
+		if err != nil {
+			return nil, fmt.Errorf("ORDER BY %s: %w", col, err)
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+// distinctOrder makes the first key of the ORDER BY clause that is on
+// the table distinct, so that its rows are sorted in one way only. A
+// bool key can not be distinct, and neither can a key that is left with
+// too few values by the sets of constraints of the rows, as in
+// WHERE a = 1, so the ties they leave are broken by the key after them
+// instead.
+func (c constrainer) distinctOrder(q *Query, scopes []*scope, sets []map[string][]types.Constraints) error {
+	arms := q.Arms()
+	for _, key := range q.GetOrderBy() {
+		refs, err := orderColumns(key, arms, scopes)
+		if err != nil {
+			return err
+		}
+		for _, ref := range refs {
+			col, err := c.column(ref)
+			if err != nil {
+				return fmt.Errorf("ORDER BY: %w", err)
+			}
+			if col != nil && col.Type != types.BoolType && c.roomFor(col, sets) {
+				col.Distinct = true
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// roomFor reports whether every set of constraints leaves the column
+// with a value of its own for every row of the table. Distinct values
+// are drawn at random, so a quarter of the values has to be left over
+// for the last row, for its draws to find one that is not taken yet.
+func (c constrainer) roomFor(col *types.Column, sets []map[string][]types.Constraints) bool {
+	rows := max(c.t.Dim.Rows, c.minRows)
+	for _, set := range sets {
+		domain, err := solver.NewDomain(col.Type)
+		if err != nil {
+			return false
+		}
+		for _, cons := range append(slices.Clip(col.Constraints), set[col.Name]...) {
+			if err := cons.Apply(domain); err != nil {
+				return false
+			}
+		}
+		if domain.IsNull() {
+			continue
+		}
+		if size := domain.Size(); size < rows || size-rows+1 < size/4 {
+			return false
+		}
+	}
+	return true
+}
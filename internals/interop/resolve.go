@@ -0,0 +1,626 @@
+package interop
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/participle/v2/lexer"
+	"github.com/phdah/sql-tdg/internals/parser"
+)
+
+// relation is a table in the FROM or JOIN clauses of a query, with the
+// alias it may be referred to by. A relation that reads from a common
+// table expression or a subquery rather than from a table holds that
+// query, and the scope it is resolved in. Derived tables are named by
+// their alias.
+type relation struct {
+	name  string
+	alias string
+
+	query   *parser.Query
+	columns []string // column list of a CTE, if it names its columns
+	scope   *scope
+}
+
+// refersTo reports whether a qualifier such as the o in o.amount, or the
+// orders in orders.amount, names the relation. A schema-qualified table
+// can also be referred to by its name alone.
+func (r relation) refersTo(qualifier string) bool {
+	if r.alias != "" && strings.EqualFold(r.alias, qualifier) {
+		return true
+	}
+	return tableIs(r.name, qualifier)
+}
+
+// tableIs reports whether the table with the full name table is the
+// table with the given name.
+func tableIs(table, name string) bool {
+	if strings.EqualFold(table, name) {
+		return true
+	}
+	parts := strings.Split(table, ".")
+	return strings.EqualFold(parts[len(parts)-1], name)
+}
+
+// cte is a common table expression, along with the CTEs that are
+// visible from its query.
+type cte struct {
+	def       *parser.CTE
+	recursive bool
+	visible   map[string]*cte
+}
+
+// scope maps the column references of a query onto the relations they
+// belong to, following references to CTEs down to the tables they read.
+// The subquery of an EXISTS or IN predicate has the scope of the query
+// it is correlated with as its parent.
+type scope struct {
+	relations []relation
+	ctes      map[string]*cte
+
+	parent *scope
+	anti   bool // the subquery is under NOT EXISTS or NOT IN
+}
+
+// withCTEs adds the CTEs of a WITH clause to the ones of the outer
+// queries.
+func withCTEs(with *parser.WithClause, ctes map[string]*cte) map[string]*cte {
+	if with == nil {
+		return ctes
+	}
+	outer := ctes
+	ctes = make(map[string]*cte, len(outer)+len(with.CTEs))
+	for name, c := range outer {
+		ctes[name] = c
+	}
+	for _, def := range with.CTEs {
+		// A CTE sees the ones defined before it
+		visible := make(map[string]*cte, len(ctes))
+		for name, c := range ctes {
+			visible[name] = c
+		}
+		ctes[strings.ToLower(def.Name)] = &cte{
+			def: def, recursive: with.Recursive, visible: visible,
+		}
+	}
+	return ctes
+}
+
+// newScope builds the scope of a nested query, such as a CTE or a
+// subquery, in which the CTEs of the outer queries are visible alongside
+// the query's own. Set operations are only supported in the outer query.
+func newScope(q *parser.Query, ctes map[string]*cte) (*scope, error) {
+	if len(q.SetOps) > 0 {
+		return nil, fmt.Errorf("%s is only supported in the outer query", strings.ToUpper(q.SetOps[0].Op))
+	}
+	return newArmScope(&q.SelectCore, withCTEs(q.With, ctes))
+}
+
+// newArmScope builds the scope of a single SELECT.
+func newArmScope(q *parser.SelectCore, ctes map[string]*cte) (*scope, error) {
+	s := scope{ctes: ctes}
+	if q.From != nil {
+		rel, err := newRelation(q.From.Table, q.From.Sub, q.From.Alias, ctes)
+		if err != nil {
+			return nil, err
+		}
+		s.relations = append(s.relations, rel)
+		for _, t := range q.From.Implicit {
+			rel, err := newRelation(t.Table, t.Sub, t.Alias, ctes)
+			if err != nil {
+				return nil, err
+			}
+			s.relations = append(s.relations, rel)
+		}
+	}
+	for _, j := range q.Joins {
+		rel, err := newRelation(j.Table, j.Sub, j.Alias, ctes)
+		if err != nil {
+			return nil, err
+		}
+		s.relations = append(s.relations, rel)
+	}
+	return &s, nil
+}
+
+func newRelation(table *parser.QIdent, sub *parser.Query, alias *string, ctes map[string]*cte) (relation, error) {
+	var rel relation
+	if alias != nil {
+		rel.alias = *alias
+	}
+	if sub != nil {
+		if alias == nil {
+			return rel, fmt.Errorf("a subquery in FROM or JOIN needs an alias")
+		}
+		inner, err := newScope(sub, ctes)
+		if err != nil {
+			return rel, fmt.Errorf("subquery %s: %w", rel.alias, err)
+		}
+		rel.name, rel.query, rel.scope = rel.alias, sub, inner
+		return rel, nil
+	}
+	rel.name = strings.Join(table.Parts, ".")
+	if len(table.Parts) != 1 {
+		return rel, nil
+	}
+	c, ok := ctes[strings.ToLower(rel.name)]
+	if !ok {
+		return rel, nil
+	}
+	if c.recursive {
+		return rel, fmt.Errorf("recursive CTE %q is not supported", c.def.Name)
+	}
+	inner, err := newScope(c.def.Query, c.visible)
+	if err != nil {
+		return rel, fmt.Errorf("CTE %s: %w", c.def.Name, err)
+	}
+	rel.query, rel.columns, rel.scope = c.def.Query, c.def.Columns, inner
+	return rel, nil
+}
+
+// base returns the name of the table the scope reads first, looking
+// through the CTEs and subqueries in its FROM clause.
+func (s *scope) base() string {
+	if len(s.relations) == 0 {
+		return ""
+	}
+	rel := s.relations[0]
+	if rel.scope != nil {
+		return rel.scope.base()
+	}
+	return rel.name
+}
+
+// reads reports whether the table with the given name is read anywhere
+// in the scope, including from within its CTEs and subqueries.
+func (s *scope) reads(name string) bool {
+	for _, rel := range s.relations {
+		if rel.scope != nil {
+			if rel.scope.reads(name) {
+				return true
+			}
+		} else if tableIs(rel.name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// columnRef is a column of a table. The table is empty when the column
+// is unqualified in a query over several tables, as any of them may
+// hold it.
+type columnRef struct {
+	table  string
+	column string
+}
+
+// in reports whether the column may belong to the table with the given
+// name.
+func (c columnRef) in(table string) bool {
+	return c.table == "" || tableIs(c.table, table)
+}
+
+// is reports whether two references name the same column.
+func (c columnRef) is(other columnRef) bool {
+	return strings.EqualFold(c.column, other.column) &&
+		(tableIs(c.table, other.table) || tableIs(other.table, c.table))
+}
+
+// resolve maps a column reference onto the table and column that hold
+// its values.
+func (s *scope) resolve(c parser.ColumnIR) (columnRef, error) {
+	if c.Table == "" {
+		switch {
+		case len(s.relations) == 1:
+			return s.relations[0].resolve(c.Name)
+		default:
+			for _, rel := range s.relations {
+				if rel.exposes(c.Name) {
+					return rel.resolve(c.Name)
+				}
+			}
+			return columnRef{column: c.Name}, nil
+		}
+	}
+	for _, rel := range s.relations {
+		if rel.refersTo(c.Table) {
+			return rel.resolve(c.Name)
+		}
+	}
+	return columnRef{}, &parser.Diagnostic{
+		Err:         fmt.Errorf("unknown table or alias %q", c.Table),
+		Suggestions: parser.Suggest(c.Table, s.names()),
+	}
+}
+
+// names lists the names the relations of the scope are referred to by.
+func (s *scope) names() []string {
+	out := make([]string, 0, len(s.relations))
+	for _, rel := range s.relations {
+		if rel.alias != "" {
+			out = append(out, rel.alias)
+		} else {
+			out = append(out, rel.name)
+		}
+	}
+	return out
+}
+
+// resolveCorrelated resolves a column reference in a subquery that may
+// refer to the queries it is correlated with, and reports whether the
+// column belongs to the subquery itself.
+func (s *scope) resolveCorrelated(ref parser.ColumnIR) (columnRef, bool, error) {
+	col, err := s.resolve(ref)
+	if err == nil || s.parent == nil {
+		return col, true, err
+	}
+	outer, _, outerErr := s.parent.resolveCorrelated(ref)
+	if outerErr != nil {
+		return columnRef{}, false, err
+	}
+	return outer, false, nil
+}
+
+// exposes reports whether a relation reading from a query has a column
+// by the given name. Relations on tables are left to the schema.
+func (r relation) exposes(column string) bool {
+	if r.scope == nil {
+		return false
+	}
+	for i, item := range r.query.Select.Items {
+		if item.Star || strings.EqualFold(r.columnName(i, item), column) {
+			return true
+		}
+	}
+	return false
+}
+
+// columnName returns the name the i'th select item of a CTE is exposed
+// under, which the column list of the CTE takes precedence over.
+func (r relation) columnName(i int, item *parser.SelectItem) string {
+	if i < len(r.columns) {
+		return r.columns[i]
+	}
+	return item.Name()
+}
+
+// resolve maps a column of the relation onto the table and column that
+// hold its values, following the projection of a CTE or subquery back
+// to the column it reads.
+func (r relation) resolve(column string) (columnRef, error) {
+	if r.scope == nil {
+		return columnRef{r.name, column}, nil
+	}
+	for i, item := range r.query.Select.Items {
+		if item.Star {
+			if r.scope.exposes(column) {
+				return r.scope.resolve(parser.ColumnIR{Name: column})
+			}
+			continue
+		}
+		if !strings.EqualFold(r.columnName(i, item), column) {
+			continue
+		}
+		source, ok := item.Column()
+		if !ok {
+			return columnRef{}, fmt.Errorf("column %s of %s is computed", column, r.name)
+		}
+		return r.scope.resolve(source)
+	}
+	return columnRef{}, fmt.Errorf("%s has no column %q", r.name, column)
+}
+
+// exposes reports whether SELECT * over the scope has a column by the
+// given name. As the columns of tables are not known here, any scope
+// reading a table may have it.
+func (s *scope) exposes(column string) bool {
+	for _, rel := range s.relations {
+		if rel.scope == nil || rel.exposes(column) {
+			return true
+		}
+	}
+	return false
+}
+
+// boundCondition is a condition along with the column it constrains,
+// the typed literals it compares the column with, and where the
+// predicate it comes from is in the query.
+type boundCondition struct {
+	columnRef
+	parser.ConditionsIR
+	operands []parser.LiteralIR
+	pos      lexer.Position
+}
+
+// keyLink ties a key column of a query to a key column of the subquery
+// of an EXISTS or IN predicate. The keys have to match for the predicate
+// to hold, and must not match when it is negated. The keys of two tables
+// joined in the same query are tied the same way, but as join, since the
+// rows of either table have to find their match.
+type keyLink struct {
+	outer, inner columnRef
+	anti, join   bool
+	pos          lexer.Position
+}
+
+// bindings holds the conditions of a query resolved onto the tables
+// they constrain, along with the keys its subqueries are joined on, the
+// keys and aggregate conditions of its groups, and the conditions on
+// its window functions. Comparisons on CASE expressions, and ORs, are
+// kept apart, as they hold in one of several ways. Pos is where the
+// predicate being bound is in the query.
+type bindings struct {
+	conditions []boundCondition
+	links      []keyLink
+	subqueries []*scope
+	groupBy    []columnRef
+	aggregates []boundCondition
+	cases      []alternatives
+	windows    []window
+	pos        lexer.Position
+}
+
+// reads reports whether the table with the given name is read by the
+// subquery of an EXISTS or IN predicate.
+func (b *bindings) reads(name string) bool {
+	for _, s := range b.subqueries {
+		if s.reads(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// bind resolves the conditions of a query, and of the CTEs and
+// subqueries it reads from, onto the tables they constrain. Rows that
+// make it through the query have to pass the inner filters as well, so
+// those apply to the tables underneath.
+func (s *scope) bind(q *parser.SelectCore, b *bindings) error {
+	keys, err := q.GetGroupBy()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		col, err := s.resolve(key)
+		if err != nil {
+			return fmt.Errorf("GROUP BY %s: %w", key, err)
+		}
+		b.groupBy = append(b.groupBy, col)
+	}
+	if err := s.bindTree(parser.PushNot(q.GetPredicate()), b); err != nil {
+		return err
+	}
+	if err := s.bindJoins(q, b); err != nil {
+		return err
+	}
+	for _, rel := range s.relations {
+		if rel.scope == nil {
+			continue
+		}
+		if err := rel.scope.bind(&rel.query.SelectCore, b); err != nil {
+			return fmt.Errorf("%s: %w", rel.name, err)
+		}
+	}
+	return nil
+}
+
+// bindJoins binds the JOIN clauses of a query, whose keys link the
+// tables the same way the equalities of a WHERE clause do. An ON clause
+// is bound as a WHERE clause is, and USING (k) as the equality of k on
+// the joined table with k on the tables before it.
+func (s *scope) bindJoins(q *parser.SelectCore, b *bindings) error {
+	first := len(s.relations) - len(q.Joins)
+	for i, j := range q.Joins {
+		if j.On != nil {
+			if err := s.bindTree(parser.PushNot(j.On.Tree()), b); err != nil {
+				return err
+			}
+		}
+		before := &scope{relations: s.relations[:first+i], ctes: s.ctes}
+		joined := s.relations[first+i]
+		for _, col := range j.Using {
+			left, err := before.resolve(parser.ColumnIR{Name: col})
+			if err != nil {
+				return parser.At(j.Pos, fmt.Errorf("USING (%s): %w", col, err))
+			}
+			right, err := joined.resolve(col)
+			if err != nil {
+				return parser.At(j.Pos, fmt.Errorf("USING (%s): %w", col, err))
+			}
+			b.links = append(b.links, keyLink{outer: left, inner: right, join: true, pos: j.Pos})
+		}
+	}
+	return nil
+}
+
+// bindTree binds the predicates of a tree without NOTs. The terms of an
+// AND all hold, while an OR holds in one of the ways its terms do, as
+// the branches of a CASE.
+func (s *scope) bindTree(t parser.BoolIR, b *bindings) error {
+	switch t := t.(type) {
+	case nil:
+		return nil
+	case *parser.AndIR:
+		for _, term := range t.Terms {
+			if err := s.bindTree(term, b); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *parser.OrIR:
+		var alts alternatives
+		for _, term := range t.Terms {
+			var inner bindings
+			if err := s.bindTree(term, &inner); err != nil {
+				return err
+			}
+			combined, err := inner.combinations()
+			if err != nil {
+				return fmt.Errorf("OR: %w", err)
+			}
+			alts = append(alts, combined...)
+		}
+		b.cases = append(b.cases, alts)
+		return nil
+	case *parser.PredIR:
+		// The predicates a CASE is expanded into keep the position of
+		// the CASE
+		if t.Pos.Line != 0 {
+			b.pos = t.Pos
+		}
+		return parser.At(t.Pos, s.bindPred(t, b))
+	default:
+		return fmt.Errorf("unexpected %s in the conditions", t)
+	}
+}
+
+// bindPred binds a predicate to the column it constrains, or to the
+// subqueries or the keys it links the tables with.
+func (s *scope) bindPred(p *parser.PredIR, b *bindings) error {
+	c := p.Condition()
+	if c.Unsupported != "" {
+		if c.Op == "CASE" {
+			return fmt.Errorf("CASE: %s", c.Unsupported)
+		}
+		return fmt.Errorf("condition %s %s %s: %s", c.Left, c.Op, c.Right, c.Unsupported)
+	}
+	switch {
+	case c.Aggregate != "":
+		return s.bindAggregate(p, b)
+	case c.Op == "CASE":
+		return s.bindCase(c, b)
+	case c.Func != nil:
+		return s.bindFunc(p, b)
+	case c.Op == "EXISTS":
+		return s.bindExists(c, b)
+	case c.Subquery != nil:
+		return s.bindIn(p, b)
+	case p.Other != nil:
+		return s.bindCorrelation(p, b)
+	case p.Column == nil:
+		return fmt.Errorf("condition %s is not on a column", p)
+	}
+	return s.bindComparison(*p.Column, c, p.Literals, b)
+}
+
+// bindComparison binds a condition that compares a column with values
+// to the column.
+func (s *scope) bindComparison(column parser.ColumnIR, c parser.ConditionsIR, operands []parser.LiteralIR, b *bindings) error {
+	col, err := s.resolve(column)
+	if err != nil {
+		return fmt.Errorf("column %s: %w", c.Left, err)
+	}
+	b.conditions = append(b.conditions, boundCondition{col, c, operands, b.pos})
+	return nil
+}
+
+// bindAggregate binds a condition on an aggregate to the column it
+// aggregates. COUNT(*) is bound to no column at all.
+func (s *scope) bindAggregate(p *parser.PredIR, b *bindings) error {
+	c := p.Condition()
+	var col columnRef
+	if p.Column != nil {
+		var err error
+		col, err = s.resolve(*p.Column)
+		if err != nil {
+			return fmt.Errorf("%s(%s): %w", c.Aggregate, c.Left, err)
+		}
+	}
+	b.aggregates = append(b.aggregates, boundCondition{col, c, p.Literals, b.pos})
+	return nil
+}
+
+// subquery builds the scope of the subquery of an EXISTS or IN predicate.
+func (s *scope) subquery(q *parser.Query, anti bool) (*scope, error) {
+	sub, err := newScope(q, s.ctes)
+	if err != nil {
+		return nil, err
+	}
+	sub.parent, sub.anti = s, anti
+	return sub, nil
+}
+
+// bindExists binds the filters of an EXISTS subquery, which link its
+// rows to the rows of the outer query through the keys it compares.
+func (s *scope) bindExists(c parser.ConditionsIR, b *bindings) error {
+	sub, err := s.subquery(c.Subquery, c.Negated)
+	if err != nil {
+		return fmt.Errorf("EXISTS: %w", err)
+	}
+	b.subqueries = append(b.subqueries, sub)
+	links := len(b.links)
+	if err := sub.bind(&c.Subquery.SelectCore, b); err != nil {
+		return fmt.Errorf("EXISTS: %w", err)
+	}
+	if c.Negated && len(b.links) == links {
+		return fmt.Errorf("NOT EXISTS without a correlated key is not supported")
+	}
+	return nil
+}
+
+// bindIn binds an IN over a subquery, which links the column on the
+// left to the single column the subquery selects.
+func (s *scope) bindIn(p *parser.PredIR, b *bindings) error {
+	c := p.Condition()
+	sub, err := s.subquery(c.Subquery, c.Negated)
+	if err != nil {
+		return fmt.Errorf("IN: %w", err)
+	}
+	b.subqueries = append(b.subqueries, sub)
+	items := c.Subquery.Select.Items
+	if len(items) != 1 {
+		return fmt.Errorf("IN: subquery has to select a single column")
+	}
+	key, ok := items[0].Column()
+	if !ok {
+		return fmt.Errorf("IN: subquery has to select a column")
+	}
+	inner, err := sub.resolve(key)
+	if err != nil {
+		return fmt.Errorf("IN: column %s: %w", key, err)
+	}
+	outer, err := s.resolve(*p.Column)
+	if err != nil {
+		return fmt.Errorf("column %s: %w", c.Left, err)
+	}
+	b.links = append(b.links, keyLink{outer: outer, inner: inner, anti: c.Negated, pos: b.pos})
+	if err := sub.bind(&c.Subquery.SelectCore, b); err != nil {
+		return fmt.Errorf("IN: %w", err)
+	}
+	return nil
+}
+
+// bindCorrelation binds a comparison between two columns, which is only
+// supported as an equality. Between the tables of a query, as in the
+// implicit join of FROM a, b WHERE a.id = b.a_id, it joins them, and
+// between a subquery and the query around it, it correlates the two.
+func (s *scope) bindCorrelation(p *parser.PredIR, b *bindings) error {
+	c := p.Condition()
+	left, leftLocal, err := s.resolveCorrelated(*p.Column)
+	if err != nil {
+		return fmt.Errorf("column %s: %w", c.Left, err)
+	}
+	right, rightLocal, err := s.resolveCorrelated(*p.Other)
+	if err != nil {
+		return fmt.Errorf("column %s: %w", c.Right, err)
+	}
+	if !leftLocal && !rightLocal {
+		return fmt.Errorf("condition %s %s %s: comparison between columns is not supported",
+			c.Left, c.Op, c.Right)
+	}
+	if leftLocal && rightLocal {
+		if c.Op != "=" || c.Negated {
+			return fmt.Errorf("condition %s %s %s: tables are only joined on =", c.Left, c.Op, c.Right)
+		}
+		b.links = append(b.links, keyLink{outer: left, inner: right, join: true, pos: b.pos})
+		return nil
+	}
+	if c.Op != "=" || c.Negated {
+		return fmt.Errorf("condition %s %s %s: subqueries are only correlated on =", c.Left, c.Op, c.Right)
+	}
+	if leftLocal {
+		left, right = right, left
+	}
+	b.links = append(b.links, keyLink{outer: left, inner: right, anti: s.anti, pos: b.pos})
+	return nil
+}
@@ -0,0 +1,147 @@
+package interop
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/phdah/sql-tdg/internals/parser"
+	"github.com/phdah/sql-tdg/internals/types"
+)
+
+// rankFuncs are the window functions that number the rows of a
+// partition. With distinct keys to order by they all number the rows
+// the same way.
+var rankFuncs = map[string]bool{
+	"ROW_NUMBER": true,
+	"RANK":       true,
+	"DENSE_RANK": true,
+}
+
+// window is a condition on the rank of the rows of a partition, as in
+// the QUALIFY ROW_NUMBER() OVER (PARTITION BY id ORDER BY ts) = 1 that
+// keeps the first row of every id. The condition is bound to no column.
+type window struct {
+	boundCondition
+	partitionBy []columnRef
+	orderBy     []columnRef
+}
+
+// bindWindow binds a condition on a window function to the columns of
+// its window.
+func (s *scope) bindWindow(p *parser.PredIR, b *bindings) error {
+	c := p.Condition()
+	f := c.Func
+	if !rankFuncs[f.Name] {
+		return fmt.Errorf("window function %s is not supported", f.Name)
+	}
+	w := window{boundCondition: boundCondition{ConditionsIR: c, operands: p.Literals, pos: b.pos}}
+	for _, key := range f.Window.PartitionBy {
+		if key.Column == nil {
+			return fmt.Errorf("PARTITION BY %s: only columns are supported", key)
+		}
+		col, err := s.resolve(*key.Column)
+		if err != nil {
+			return fmt.Errorf("PARTITION BY %s: %w", key, err)
+		}
+		w.partitionBy = append(w.partitionBy, col)
+	}
+	for _, key := range f.Window.OrderBy {
+		if key.Column == nil {
+			continue
+		}
+		col, err := s.resolve(*key.Column)
+		if err != nil {
+			return fmt.Errorf("ORDER BY %s: %w", key.Column, err)
+		}
+		w.orderBy = append(w.orderBy, col)
+	}
+	b.windows = append(b.windows, w)
+	return nil
+}
+
+// rankRows returns the number of rows a partition needs for a condition
+// on the rank of its rows to let some of them through and keep the
+// others out, so a partition of ROW_NUMBER() ... = 1 gets two rows.
+func rankRows(w window) (int, error) {
+	c := w.ConditionsIR
+	op, n, err := intOperands(c, w.operands)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", c.Func.Name, err)
+	}
+	none := fmt.Errorf("the %s condition leaves no rows", c.Func.Name)
+	switch op {
+	case "=", "<=":
+		if n[0] < 1 {
+			return 0, none
+		}
+		return n[0] + 1, nil
+	case "<":
+		if n[0] <= 1 {
+			return 0, none
+		}
+		return n[0], nil
+	case ">":
+		return max(n[0]+1, 2), nil
+	case ">=", "!=", "<>":
+		return max(n[0], 2), nil
+	case "BETWEEN":
+		if n[0] > n[1] || n[1] < 1 {
+			return 0, none
+		}
+		return n[1] + 1, nil
+	default:
+		return 0, fmt.Errorf("%s %s is not supported", c.Func.Name, op)
+	}
+}
+
+// window splits the rows of the table into partitions for a condition
+// on a window function. Each partition gets its own value for the
+// partition keys on the table, and enough rows for the condition to
+// keep some of them out, while the key the rows are ordered by is made
+// distinct when it has the values for it, so that they are ranked in
+// one way only. A window over the
+// other tables of the query leaves the rows as they are.
+func (c constrainer) window(set map[string][]types.Constraints, windows []window) ([]map[string][]types.Constraints, error) {
+	if len(windows) > 1 {
+		return nil, fmt.Errorf("more than one condition on a window function is not supported")
+	}
+	w := windows[0]
+	var keys []*types.Column
+	for _, key := range w.partitionBy {
+		col, err := c.column(key)
+		if err != nil {
+			return nil, fmt.Errorf("PARTITION BY: %w", err)
+		}
+		if col != nil {
+			keys = append(keys, col)
+		}
+	}
+	var order *types.Column
+	for _, key := range w.orderBy {
+		col, err := c.column(key)
+		if err != nil {
+			return nil, fmt.Errorf("ORDER BY: %w", err)
+		}
+		if col != nil && col.Type != types.BoolType {
+			order = col
+			break
+		}
+	}
+	if len(keys) == 0 && order == nil {
+		return []map[string][]types.Constraints{set}, nil
+	}
+
+	rows, err := rankRows(w)
+	if err != nil {
+		return nil, err
+	}
+	_, partitions := c.split(len(keys) > 0, rows, math.MaxInt)
+	sets, err := c.splitKeys(set, keys, partitions, "PARTITION BY")
+	if err != nil {
+		return nil, err
+	}
+	if order != nil && c.roomFor(order, sets) {
+		order.Distinct = true
+	}
+	return sets, nil
+}
@@ -0,0 +1,417 @@
+package parser
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// flipped maps a comparison operator onto the operator that keeps the
+// comparison true when its two sides swap places, so 10 < a becomes
+// a > 10.
+var flipped = map[string]string{
+	"=":  "=",
+	"!=": "!=",
+	"<>": "<>",
+	"<":  ">",
+	"<=": ">=",
+	">":  "<",
+	">=": "<=",
+}
+
+// linear is a linear combination of columns plus a constant, that is
+// the sum of Coefs[col] * col over all columns, plus Const.
+type linear struct {
+	Coefs map[string]float64
+	Const float64
+}
+
+func constant(v float64) linear {
+	return linear{Coefs: map[string]float64{}, Const: v}
+}
+
+// scale multiplies every part of the combination by k.
+func (l linear) scale(k float64) linear {
+	out := constant(l.Const * k)
+	for col, coef := range l.Coefs {
+		if coef*k != 0 {
+			out.Coefs[col] = coef * k
+		}
+	}
+	return out
+}
+
+// add returns l + k * other.
+func (l linear) add(other linear, k float64) linear {
+	out := l.scale(1)
+	out.Const += k * other.Const
+	for col, coef := range other.Coefs {
+		out.Coefs[col] += k * coef
+		if out.Coefs[col] == 0 {
+			delete(out.Coefs, col)
+		}
+	}
+	return out
+}
+
+// columns returns the sorted names of the columns in the combination.
+func (l linear) columns() []string {
+	return slices.Sorted(maps.Keys(l.Coefs))
+}
+
+func (l linear) isConstant() bool {
+	return len(l.Coefs) == 0
+}
+
+// integral reports whether every part of the combination is an int, so
+// that it only takes int values over int columns.
+func (l linear) integral() bool {
+	if l.Const != math.Trunc(l.Const) {
+		return false
+	}
+	for _, coef := range l.Coefs {
+		if coef != math.Trunc(coef) {
+			return false
+		}
+	}
+	return true
+}
+
+// isInt reports whether a constant operand is an int, which it is when
+// its value is whole and it is not written with a decimal point.
+func isInt(p *Primary, v linear) bool {
+	return v.integral() && !strings.Contains(parenAtom(p), ".")
+}
+
+// primary returns the single Primary of an Arith without any operators,
+// or nil if the Arith is a real arithmetic expression.
+func (a *Arith) primary() *Primary {
+	if a == nil || len(a.Rest) > 0 || a.Left == nil || len(a.Left.Rest) > 0 {
+		return nil
+	}
+	return a.Left.Left
+}
+
+// arith returns the arithmetic expression of an Expr that holds nothing
+// else, as in the parenthesised (a + 5) of (a + 5) * 2, or nil.
+func (e *Expr) arith() *Arith {
+	if e == nil || len(e.Rest) > 0 || e.Left == nil || len(e.Left.Rest) > 0 {
+		return nil
+	}
+	n := e.Left.Left
+	if n == nil || n.Cmp == nil || n.Cmp.Op != nil || n.Cmp.In != nil ||
+		n.Cmp.Between != nil || n.Cmp.Is != nil || n.Cmp.Like != nil {
+		return nil
+	}
+	return n.Cmp.Left
+}
+
+// arithAtom converts an Arith expression into its string representation,
+// which for a lone operand is the same as primaryAtom.
+func arithAtom(a *Arith) string {
+	if a == nil {
+		return ""
+	}
+	parts := []string{termAtom(a.Left)}
+	for _, r := range a.Rest {
+		parts = append(parts, r.Op, termAtom(r.Right))
+	}
+	return strings.Join(parts, " ")
+}
+
+func termAtom(t *Term) string {
+	if t == nil {
+		return ""
+	}
+	parts := []string{parenAtom(t.Left)}
+	for _, r := range t.Rest {
+		parts = append(parts, r.Op, parenAtom(r.Right))
+	}
+	return strings.Join(parts, " ")
+}
+
+// parenAtom is primaryAtom, extended to parenthesised arithmetic.
+func parenAtom(p *Primary) string {
+	if p != nil && p.Paren != nil {
+		if inner := p.Paren.arith(); inner != nil {
+			return "(" + arithAtom(inner) + ")"
+		}
+	}
+	return primaryAtom(p)
+}
+
+func (a *Arith) linear() (linear, error) {
+	out, err := a.Left.linear()
+	if err != nil {
+		return linear{}, err
+	}
+	for _, r := range a.Rest {
+		right, err := r.Right.linear()
+		if err != nil {
+			return linear{}, err
+		}
+		if r.Op == "+" {
+			out = out.add(right, 1)
+		} else {
+			out = out.add(right, -1)
+		}
+	}
+	return out, nil
+}
+
+func (t *Term) linear() (linear, error) {
+	out, err := t.Left.linear()
+	if err != nil {
+		return linear{}, err
+	}
+	for _, r := range t.Rest {
+		right, err := r.Right.linear()
+		if err != nil {
+			return linear{}, err
+		}
+		switch r.Op {
+		case "*":
+			switch {
+			case right.isConstant():
+				out = out.scale(right.Const)
+			case out.isConstant():
+				out = right.scale(out.Const)
+			default:
+				return linear{}, fmt.Errorf("product of columns in %s is not linear", termAtom(t))
+			}
+		case "/":
+			if !right.isConstant() {
+				return linear{}, fmt.Errorf("division by %s is not linear", parenAtom(r.Right))
+			}
+			if right.Const == 0 {
+				return linear{}, fmt.Errorf("division by zero")
+			}
+			if !isInt(r.Right, right) || !out.integral() {
+				out = out.scale(1 / right.Const)
+				continue
+			}
+			// A division of ints truncates the quotient towards zero
+			if !out.isConstant() {
+				return linear{}, fmt.Errorf("division of ints in %s truncates, which is only supported on its own on one side of a comparison", termAtom(t))
+			}
+			out = constant(math.Trunc(out.Const / right.Const))
+		case "%":
+			if !out.isConstant() || !right.isConstant() || right.Const == 0 {
+				return linear{}, fmt.Errorf("%% is only supported between non zero constants")
+			}
+			// The remainder takes the sign of the dividend, as in SQL
+			out = constant(math.Mod(out.Const, right.Const))
+		}
+	}
+	return out, nil
+}
+
+func (p *Primary) linear() (linear, error) {
+	switch {
+	case p.QIdent != nil:
+		return linear{Coefs: map[string]float64{primaryAtom(p): 1}}, nil
+	case p.Num != nil:
+		v, err := strconv.ParseFloat(*p.Num, 64)
+		if err != nil {
+			return linear{}, err
+		}
+		return constant(v), nil
+	case p.Paren != nil:
+		if inner := p.Paren.arith(); inner != nil {
+			return inner.linear()
+		}
+		return linear{}, fmt.Errorf("condition used as a number")
+	case p.isCast():
+		// A cast keeps the value of a number, if not its type
+		if inner, _ := p.uncast(); inner != p {
+			return inner.linear()
+		}
+		if inner := p.Cast.Expr.arith(); inner != nil {
+			return inner.linear()
+		}
+		return linear{}, fmt.Errorf("condition used as a number")
+	default:
+		return linear{}, fmt.Errorf("%s can not be used in arithmetic", primaryAtom(p))
+	}
+}
+
+// formatNumber renders a number the way it would be written in a query,
+// so 15.0 becomes 15 and -0 becomes 0.
+func formatNumber(v float64) string {
+	if v == 0 {
+		v = 0
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// arithToIR isolates the single column in a comparison between two
+// arithmetic expressions, turning a + 5 > 20 into a > 15. The sides are
+// moved over to the left as in a + 5 - 20 > 0, and the operator flips
+// when the column ends up with a negative coefficient. A division by a
+// number with a decimal point is exact, so a / 2.0 > 5 becomes a > 10,
+// while a division of ints truncates, as truncatedToIR handles.
+// Comparisons that are not linear, or that do not hold exactly one
+// column, are returned with the reason in Unsupported.
+func arithToIR(c *Cmp, negated bool) ConditionsIR {
+	if out, ok := truncatedToIR(c, negated); ok {
+		return out
+	}
+	unsupported := ConditionsIR{
+		Left:    LeftIR(arithAtom(c.Left)),
+		Op:      OpIR(*c.Op),
+		Right:   RightIR(arithAtom(c.Right)),
+		Negated: negated,
+	}
+	left, err := c.Left.linear()
+	if err != nil {
+		unsupported.Unsupported = err.Error()
+		return unsupported
+	}
+	right, err := c.Right.linear()
+	if err != nil {
+		unsupported.Unsupported = err.Error()
+		return unsupported
+	}
+	diff := left.add(right, -1)
+
+	cols := diff.columns()
+	switch len(cols) {
+	case 0:
+		unsupported.Unsupported = "comparison between constants"
+		return unsupported
+	case 1:
+	default:
+		unsupported.Unsupported = fmt.Sprintf(
+			"comparison over more than one column (%s)", strings.Join(cols, ", "))
+		return unsupported
+	}
+
+	col := cols[0]
+	coef := diff.Coefs[col]
+	op := *c.Op
+	if coef < 0 {
+		op = flipped[op]
+	}
+	return ConditionsIR{
+		Left:    LeftIR(col),
+		Op:      OpIR(op),
+		Right:   RightIR(formatNumber(-diff.Const / coef)),
+		Negated: negated,
+	}
+}
+
+// division returns the dividend and the divisor of an arithmetic
+// expression that is a single division, as in (a + 1) / 2, or false.
+func (a *Arith) division() (*Term, *Primary, bool) {
+	if a == nil || len(a.Rest) > 0 || a.Left == nil {
+		return nil, nil, false
+	}
+	t := a.Left
+	if len(t.Rest) == 0 {
+		if t.Left != nil && t.Left.Paren != nil {
+			return t.Left.Paren.arith().division()
+		}
+		return nil, nil, false
+	}
+	last := t.Rest[len(t.Rest)-1]
+	if last.Op != "/" {
+		return nil, nil, false
+	}
+	return &Term{Left: t.Left, Rest: t.Rest[:len(t.Rest)-1]}, last.Right, true
+}
+
+// truncatedToIR isolates the column in a comparison between a division
+// of ints and a constant, as in a / 4 >= 3. The quotient is truncated
+// towards zero, so it is first bound to the ints that meet the
+// comparison, and those bounds to the dividend: a / 4 >= 2.5 holds
+// for the quotients from 3 up, so for a >= 12, while a / 4 < 0 only
+// holds for a <= -4. It reports false for any other comparison.
+func truncatedToIR(c *Cmp, negated bool) (ConditionsIR, bool) {
+	op := *c.Op
+	dividend, divisor, ok := c.Left.division()
+	other := c.Right
+	if !ok {
+		dividend, divisor, ok = c.Right.division()
+		other, op = c.Left, flipped[op]
+	}
+	if !ok {
+		return ConditionsIR{}, false
+	}
+	num, err := dividend.linear()
+	if err != nil || len(num.Coefs) != 1 || !num.integral() {
+		return ConditionsIR{}, false
+	}
+	den, err := divisor.linear()
+	if err != nil || !den.isConstant() || den.Const == 0 || !isInt(divisor, den) {
+		return ConditionsIR{}, false
+	}
+	bound, err := other.linear()
+	if err != nil || !bound.isConstant() {
+		return ConditionsIR{}, false
+	}
+
+	k, v := den.Const, bound.Const
+	if k < 0 {
+		// a / -k is -(a / k)
+		k, v, op = -k, -v, flipped[op]
+	}
+	// lowest and highest are the smallest and the largest dividend with
+	// the quotient m
+	lowest := func(m float64) float64 {
+		if m > 0 {
+			return m * k
+		}
+		return (m-1)*k + 1
+	}
+	highest := func(m float64) float64 {
+		if m < 0 {
+			return m * k
+		}
+		return (m+1)*k - 1
+	}
+	var bounds []float64
+	switch op {
+	case ">":
+		op, bounds = ">=", []float64{lowest(math.Floor(v) + 1)}
+	case ">=":
+		bounds = []float64{lowest(math.Ceil(v))}
+	case "<":
+		op, bounds = "<=", []float64{highest(math.Ceil(v) - 1)}
+	case "<=":
+		bounds = []float64{highest(math.Floor(v))}
+	default:
+		if op != "=" {
+			// The quotient differs from v when it is not equal to it
+			negated = !negated
+		}
+		op = "BETWEEN"
+		if v != math.Trunc(v) {
+			// No quotient of ints is equal to v
+			return ConditionsIR{Left: LeftIR(num.columns()[0]), Op: "IN", Values: []RightIR{}, Negated: negated}, true
+		}
+		bounds = []float64{lowest(v), highest(v)}
+	}
+
+	col := num.columns()[0]
+	coef := num.Coefs[col]
+	values := make([]RightIR, len(bounds))
+	for i, b := range bounds {
+		values[i] = RightIR(formatNumber((b - num.Const) / coef))
+	}
+	out := ConditionsIR{Left: LeftIR(col), Op: OpIR(op), Negated: negated}
+	switch {
+	case op == "BETWEEN":
+		if coef < 0 {
+			slices.Reverse(values)
+		}
+		out.Values = values
+	case coef < 0:
+		out.Op, out.Right = OpIR(flipped[op]), values[0]
+	default:
+		out.Right = values[0]
+	}
+	return out, true
+}
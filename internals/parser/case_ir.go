@@ -0,0 +1,147 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+// caseSide returns the CASE expression of a comparison such as
+// CASE ... END > 100, along with the other side of the comparison and
+// the operator seen from the CASE. The operator is flipped when the CASE
+// is on the right.
+func (c *Cmp) caseSide() (*Case, *Arith, string, bool) {
+	if p := c.Left.primary(); p != nil && p.Case != nil {
+		return p.Case, c.Right, *c.Op, true
+	}
+	if p := c.Right.primary(); p != nil && p.Case != nil {
+		return p.Case, c.Left, flipped[*c.Op], true
+	}
+	return nil, nil, "", false
+}
+
+// caseToIR expands a comparison on a CASE expression into one
+// alternative for each of its branches. A branch is taken when its WHEN
+// holds and the ones before it do not, and its value has to pass the
+// comparison. Branches whose value never passes are left out, as are
+// the rows that reach the end of a CASE without an ELSE, whose NULL
+// passes no comparison. A WHEN is kept from holding by negating it as a
+// whole, so the branch after WHEN a > 1 OR b > 2 needs a <= 1 AND
+// b <= 2. Negation only applies to the values, as the rows of NOT
+// (CASE ... END > 100) still go through one of the branches.
+func caseToIR(cs *Case, op string, other *Arith, negated bool) ConditionsIR {
+	out := ConditionsIR{Left: "CASE", Op: "CASE"}
+	var failed []BoolIR
+	addBranch := func(guard BoolIR, value *Expr) {
+		cmp := &Cmp{Left: value.arith(), Op: &op, Right: other}
+		if cmp.Left == nil {
+			out.Unsupported = "CASE values have to be numbers, strings or columns"
+			return
+		}
+		terms := slices.Clip(failed)
+		if guard != nil {
+			terms = append(terms, guard)
+		}
+		if holds, ok := constantCmp(cmp); ok {
+			if holds != negated {
+				out.Alternatives = append(out.Alternatives, and(terms...))
+			}
+			return
+		}
+		out.Alternatives = append(out.Alternatives, and(append(terms, newPred(cmp, negated))...))
+	}
+
+	for _, w := range cs.Whens {
+		guard := cs.whenTree(w)
+		addBranch(guard, w.Then)
+		failed = append(failed, PushNot(&NotIR{Term: guard}))
+	}
+	if cs.Else != nil {
+		addBranch(nil, cs.Else)
+	}
+
+	for _, alt := range out.Alternatives {
+		for _, p := range leaves(alt) {
+			if p.cond.Unsupported != "" && out.Unsupported == "" {
+				out.Unsupported = p.cond.Unsupported
+			}
+		}
+	}
+	if out.Unsupported == "" && len(out.Alternatives) == 0 {
+		out.Unsupported = "no branch of the CASE passes the comparison"
+	}
+	return out
+}
+
+// whenTree returns the condition of a WHEN, which for a simple CASE
+// compares the operand to the value of the WHEN.
+func (cs *Case) whenTree(w *When) BoolIR {
+	if cs.Operand == nil {
+		return w.Cond.Tree()
+	}
+	eq := "="
+	cmp := &Cmp{Left: cs.Operand.arith(), Op: &eq, Right: w.Cond.arith()}
+	if cmp.Left == nil || cmp.Right == nil {
+		return predOf(ConditionsIR{
+			Left: "CASE", Op: "CASE", Unsupported: "the operand of a simple CASE has to be a column",
+		}, lexer.Position{}, nil)
+	}
+	return newPred(cmp, false)
+}
+
+// constantCmp evaluates a comparison between two constants, and reports
+// whether it could, which it can not when either side holds a column.
+func constantCmp(c *Cmp) (bool, bool) {
+	left, right := c.Left.primary(), c.Right.primary()
+	if left != nil && right != nil && left.Str != nil && right.Str != nil {
+		return compare(*c.Op, cmpStrings(*left.Str, *right.Str)), true
+	}
+	l, err := c.Left.linear()
+	if err != nil || !l.isConstant() {
+		return false, false
+	}
+	r, err := c.Right.linear()
+	if err != nil || !r.isConstant() {
+		return false, false
+	}
+	diff := l.Const - r.Const
+	sign := 0
+	switch {
+	case diff < 0:
+		sign = -1
+	case diff > 0:
+		sign = 1
+	}
+	return compare(*c.Op, sign), true
+}
+
+func cmpStrings(a, b string) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compare reports whether an operator holds between two values, given
+// the sign of their difference.
+func compare(op string, sign int) bool {
+	switch op {
+	case "=":
+		return sign == 0
+	case "!=", "<>":
+		return sign != 0
+	case "<":
+		return sign < 0
+	case "<=":
+		return sign <= 0
+	case ">":
+		return sign > 0
+	case ">=":
+		return sign >= 0
+	}
+	panic(fmt.Sprintf("unknown comparison operator %q", op))
+}
@@ -0,0 +1,41 @@
+package parser
+
+import "strings"
+
+// String returns the lower-cased name of a type, without its parameters,
+// so DECIMAL(10, 2) is decimal.
+func (t *TypeName) String() string {
+	return strings.ToLower(t.Name)
+}
+
+// uncast strips the casts off a Primary, as in CAST(ts AS DATE) or
+// ts::date, and returns the value underneath along with the type of the
+// outermost cast. A Primary without a cast comes back as it is, with no
+// type, and so does a cast over anything but a single operand.
+func (p *Primary) uncast() (*Primary, string) {
+	var inner *Primary
+	var typ string
+	switch {
+	case p == nil:
+		return nil, ""
+	case len(p.Casts) > 0:
+		bare := *p
+		bare.Casts = nil
+		inner, typ = &bare, p.Casts[len(p.Casts)-1].String()
+	case p.Cast != nil:
+		inner, typ = p.Cast.Expr.arith().primary(), p.Cast.Type.String()
+	default:
+		return p, ""
+	}
+	if inner == nil {
+		return p, typ
+	}
+	inner, _ = inner.uncast()
+	return inner, typ
+}
+
+// isCast reports whether a Primary is still a cast after uncast, which
+// leaves the casts over arithmetic or conditions in place.
+func (p *Primary) isCast() bool {
+	return p != nil && (p.Cast != nil || len(p.Casts) > 0)
+}
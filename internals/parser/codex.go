@@ -1,102 +1,306 @@
 package parser
 
 import (
+	"strings"
+
 	"github.com/alecthomas/participle/v2"
 	"github.com/alecthomas/participle/v2/lexer"
 )
 
 /* ---------- Lexer ---------- */
 
-var SqlLex = lexer.MustSimple([]lexer.SimpleRule{
-	{Name: "WS", Pattern: `[ \t\r\n]+`},
-	{Name: "LineComment", Pattern: `--[^\n]*`},
+// Keywords are the words every dialect reserves. They are lexed as
+// Keyword tokens, so they can never be mistaken for an identifier such
+// as a table alias, and are matched case-insensitively. A Dialect may
+// reserve more, such as QUALIFY. The other words of the grammar, such as
+// LEFT or LIMIT, are only read as keywords where no identifier may
+// stand, so they still name columns and functions, as in left(s, 3).
+// TRY_CAST and SAFE_CAST are reserved in every dialect, so that the one
+// a dialect lacks is reported as such rather than read as a function.
+var Keywords = []string{
+	"select", "from", "where", "join", "full", "outer", "inner", "cross", "natural", "on", "using", "and", "or", "not", "in", "between", "is", "null", "like", "as", "with", "recursive", "exists", "union", "intersect", "except", "distinct", "group", "having", "order", "asc", "desc", "case", "when", "then", "else", "cast", "try_cast", "safe_cast", "over",
+}
+
+// SqlLex is the lexer of the Generic dialect.
+var SqlLex = Generic.lexer()
 
-	{Name: "String", Pattern: `'([^']|'')*'|"([^"]|"")*"`},
-	{Name: "Int", Pattern: `\d+`},
-	{Name: "Ident", Pattern: `[A-Za-z_][A-Za-z0-9_]*`},
+// newLexer builds a lexer that quotes strings and identifiers with the
+// given patterns, and reserves the given keywords.
+func newLexer(strs, idents string, keywords []string) *lexer.StatefulDefinition {
+	return lexer.MustSimple([]lexer.SimpleRule{
+		{Name: "WS", Pattern: `[ \t\r\n]+`},
+		{Name: "LineComment", Pattern: `--[^\n]*`},
+		{Name: "BlockComment", Pattern: `/\*([^*]|\*+[^*/])*\*+/`},
 
-	// ONLY here for comparisons (order matters: this must come before Sym)
-	{Name: "CmpOp", Pattern: `<=|>=|<>|!=|=|<|>`},
+		{Name: "String", Pattern: strs},
+		{Name: "QuotedIdent", Pattern: idents},
+		{Name: "Number", Pattern: `(\d+(\.\d*)?|\.\d+)([eE][-+]?\d+)?`},
+		{Name: "Keyword", Pattern: `(?i)(` + strings.Join(keywords, "|") + `)\b`},
+		{Name: "Ident", Pattern: `[A-Za-z_][A-Za-z0-9_]*`},
 
-	// Punctuation (NO = < > here)
-	{Name: "Sym", Pattern: `\*|,|\.|\(|\)`},
-})
+		// ONLY here for comparisons (order matters: this must come before Sym)
+		{Name: "CmpOp", Pattern: `<=|>=|<>|!=|=|<|>`},
+
+		// Punctuation (NO = < > here)
+		{Name: "Sym", Pattern: `::|\*|,|\.|\(|\)|-|\+|/|%`},
+	})
+}
 
 /* ---------- Grammar ---------- */
 
+// Query is a SELECT, or a compound of several SELECT arms joined by set
+// operations. The first arm is embedded, so a plain query reads as one.
+// ORDER BY, LIMIT and OFFSET apply to the result of the whole query.
 type Query struct {
+	With *WithClause `parser:"( 'WITH' @@ )?"`
+	SelectCore
+	SetOps  []*SetOp     `parser:"@@*"`
+	OrderBy []*OrderItem `parser:"( 'ORDER' 'BY' @@ ( ',' @@ )* )?"`
+	Limit   *int         `parser:"( 'LIMIT' @Number )?"`
+	Offset  *int         `parser:"( 'OFFSET' @Number )?"`
+}
+
+type SelectCore struct {
 	Select  *SelectClause `parser:"'SELECT' @@"`
 	From    *FromClause   `parser:"'FROM' @@"`
 	Joins   []*JoinClause `parser:"@@*"`
 	Where   *Expr         `parser:"( 'WHERE' @@ )?"`
-	Qualify *Expr         `parser:"( 'QUALIFY' @@ )?"`
+	GroupBy []*Expr       `parser:"( 'GROUP' 'BY' @@ ( ',' @@ )* )?"`
+	Having  *Expr         `parser:"( 'HAVING' @@ )?"`
+	Qualify *Expr         `parser:"( 'QUALIFY':Keyword @@ )?"`
+}
+
+type OrderItem struct {
+	Expr *Expr `parser:"@@"`
+	Desc bool  `parser:"( @'DESC' | 'ASC' )?"`
+}
+
+type SetOp struct {
+	Op  string      `parser:"@( 'UNION' | 'INTERSECT' | 'EXCEPT' )"`
+	All bool        `parser:"( @'ALL' | 'DISTINCT' )?"`
+	Arm *SelectCore `parser:"@@"`
+}
+
+type WithClause struct {
+	Recursive bool   `parser:"@'RECURSIVE'?"`
+	CTEs      []*CTE `parser:"@@ ( ',' @@ )*"`
+}
+type CTE struct {
+	Name    string   `parser:"@( Ident | QuotedIdent )"`
+	Columns []string `parser:"( '(' @( Ident | QuotedIdent ) ( ',' @( Ident | QuotedIdent ) )* ')' )?"`
+	Query   *Query   `parser:"'AS' '(' @@ ')'"`
 }
 
 type SelectClause struct {
-	Items []*Expr `parser:"@@ ( ',' @@ )*"`
+	Items []*SelectItem `parser:"@@ ( ',' @@ )*"`
+}
+type SelectItem struct {
+	Star  bool    `parser:"  @'*'"`
+	Expr  *Expr   `parser:"| @@"`
+	Alias *string `parser:"( 'AS'? @( Ident | QuotedIdent ) )?"`
 }
+
+// FromClause is the first table of the FROM clause, followed by the
+// tables it is implicitly joined with, as in FROM a, b.
 type FromClause struct {
-	Table *QIdent `parser:"@@"`
+	Table    *QIdent     `parser:"( @@"`
+	Sub      *Query      `parser:"| '(' @@ ')' )"`
+	Alias    *string     `parser:"( 'AS'? (?! 'LEFT':Ident | 'RIGHT':Ident | 'LIMIT':Ident | 'OFFSET':Ident ) @( Ident | QuotedIdent ) )?"`
+	Implicit []*TableRef `parser:"( ',' @@ )*"`
+}
+type TableRef struct {
+	Table *QIdent `parser:"( @@"`
+	Sub   *Query  `parser:"| '(' @@ ')' )"`
+	Alias *string `parser:"( 'AS'? (?! 'LEFT':Ident | 'RIGHT':Ident | 'LIMIT':Ident | 'OFFSET':Ident ) @( Ident | QuotedIdent ) )?"`
 }
 
 type JoinClause struct {
+	Pos lexer.Position
+
 	Type  *JoinType `parser:"@@? 'JOIN'"`
-	Table *QIdent   `parser:"@@"`
+	Table *QIdent   `parser:"( @@"`
+	Sub   *Query    `parser:"| '(' @@ ')' )"`
+	Alias *string   `parser:"( 'AS'? (?! 'LEFT':Ident | 'RIGHT':Ident | 'LIMIT':Ident | 'OFFSET':Ident ) @( Ident | QuotedIdent ) )?"`
 	On    *Expr     `parser:"( 'ON' @@ )?"`
-	Using []string  `parser:"( 'USING' '(' @Ident ( ',' @Ident )* ')' )?"`
+	Using []string  `parser:"( 'USING' '(' @( Ident | QuotedIdent ) ( ',' @( Ident | QuotedIdent ) )* ')' )?"`
 }
 type JoinType struct {
-	Left  bool `parser:"  ( @'LEFT'  ( 'OUTER' )? )"`
-	Right bool `parser:"| ( @'RIGHT' ( 'OUTER' )? )"`
-	Full  bool `parser:"| ( @'FULL'  ( 'OUTER' )? )"`
-	Inner bool `parser:"| @'INNER'"`
-	Cross bool `parser:"| @'CROSS'"`
-	Nat   bool `parser:"| @'NATURAL'"`
+	Nat   bool `parser:"@'NATURAL'?"`
+	Left  bool `parser:"(   ( @'LEFT'  ( 'OUTER' )? )"`
+	Right bool `parser:"  | ( @'RIGHT' ( 'OUTER' )? )"`
+	Full  bool `parser:"  | ( @'FULL'  ( 'OUTER' )? )"`
+	Inner bool `parser:"  | @'INNER'"`
+	Cross bool `parser:"  | @'CROSS' )?"`
 }
 
 type QIdent struct {
-	Parts []string `parser:"@Ident ( '.' @Ident )*"`
+	Parts []string `parser:"@( Ident | QuotedIdent ) ( '.' @( Ident | QuotedIdent ) )*"`
 }
 
 /* ---- Expressions (no left recursion) ---- */
 
 type Expr struct {
-	Left *And `parser:"@@"`
-	Rest []*struct {
-		Op    string `parser:"@'OR'"`
-		Right *And   `parser:"@@"`
-	} `parser:"@@*"`
+	Left *And     `parser:"@@"`
+	Rest []*OrArm `parser:"@@*"`
+}
+
+// OrArm is a term of an Expr after the first. The terms of the
+// expressions are named types, as participle cannot name an anonymous
+// struct in what it reports it expected.
+type OrArm struct {
+	Op    string `parser:"@'OR'"`
+	Right *And   `parser:"@@"`
 }
 type And struct {
-	Left *Cmp `parser:"@@"`
-	Rest []*struct {
-		Op    string `parser:"@'AND'"`
-		Right *Cmp   `parser:"@@"`
-	} `parser:"@@*"`
+	Left *Not      `parser:"@@"`
+	Rest []*AndArm `parser:"@@*"`
+}
+type AndArm struct {
+	Op    string `parser:"@'AND'"`
+	Right *Not   `parser:"@@"`
+}
+type Not struct {
+	Pos lexer.Position
+
+	Not    *Not   `parser:"  'NOT' @@"`
+	Exists *Query `parser:"| 'EXISTS' '(' @@ ')'"`
+	Cmp    *Cmp   `parser:"| @@"`
 }
 type Cmp struct {
-	Left  *Primary `parser:"@@"`
-	Op    *string  `parser:"( @CmpOp"`
-	Right *Primary `parser:"  @@ )?"`
+	Pos lexer.Position
+
+	Left    *Arith   `parser:"@@"`
+	Op      *string  `parser:"( @CmpOp"`
+	Right   *Arith   `parser:"  @@"`
+	In      *InList  `parser:"| @@"`
+	Between *Between `parser:"| @@"`
+	Is      *IsNull  `parser:"| @@"`
+	Like    *Like    `parser:"| @@ )?"`
+}
+type Like struct {
+	Not     bool     `parser:"@'NOT'?"`
+	Op      string   `parser:"@( 'LIKE' | 'ILIKE':Keyword )"`
+	Pattern *Primary `parser:"@@"`
+}
+type IsNull struct {
+	Not bool `parser:"'IS' @'NOT'? 'NULL'"`
+}
+type Between struct {
+	Not  bool     `parser:"@'NOT'? 'BETWEEN'"`
+	Low  *Primary `parser:"@@"`
+	High *Primary `parser:"'AND' @@"`
+}
+type InList struct {
+	Not    bool       `parser:"@'NOT'? 'IN'"`
+	Sub    *Query     `parser:"'(' ( @@"`
+	Values []*Primary `parser:"    | @@ ( ',' @@ )* ) ')'"`
+}
+
+/* ---- Arithmetic (* / % bind tighter than + -) ---- */
+
+type Arith struct {
+	Left *Term     `parser:"@@"`
+	Rest []*AddArm `parser:"@@*"`
+}
+type AddArm struct {
+	Op    string `parser:"@( '+' | '-' )"`
+	Right *Term  `parser:"@@"`
+}
+type Term struct {
+	Left *Primary  `parser:"@@"`
+	Rest []*MulArm `parser:"@@*"`
+}
+type MulArm struct {
+	Op    string   `parser:"@( '*' | '/' | '%' )"`
+	Right *Primary `parser:"@@"`
 }
 type Primary struct {
-	QIdent *QIdent `parser:"  @@"`
-	Num    *string `parser:"| @Int"`
-	Str    *string `parser:"| @String"`
-	Paren  *Expr   `parser:"| '(' @@ ')'"`
-	Func   *Func   `parser:"| @@"`
+	Func   *Func       `parser:"( @@"`
+	QIdent *QIdent     `parser:"| @@"`
+	Num    *string     `parser:"| @( '-' | '+' )? @Number"`
+	Str    *string     `parser:"| @String"`
+	Paren  *Expr       `parser:"| '(' @@ ')'"`
+	Case   *Case       `parser:"| @@"`
+	Cast   *Cast       `parser:"| @@ )"`
+	Casts  []*TypeName `parser:"( '::' @@ )*"`
+}
+
+// Cast is a CAST, or a TRY_CAST or SAFE_CAST, which gives NULL rather
+// than an error for a value it cannot convert. Try holds the word of the
+// latter, which only the dialects that have it accept.
+type Cast struct {
+	Pos lexer.Position
+
+	Try  string    `parser:"( @( 'TRY_CAST' | 'SAFE_CAST' ) | 'CAST' )"`
+	Expr *Expr     `parser:"'(' @@"`
+	Type *TypeName `parser:"'AS' @@ ')'"`
+}
+type TypeName struct {
+	Name   string   `parser:"@Ident"`
+	Params []string `parser:"( '(' @Number ( ',' @Number )* ')' )?"`
+}
+type Case struct {
+	Operand *Expr   `parser:"'CASE' @@?"`
+	Whens   []*When `parser:"@@+"`
+	Else    *Expr   `parser:"( 'ELSE' @@ )? 'END'"`
+}
+type When struct {
+	Cond *Expr `parser:"'WHEN' @@"`
+	Then *Expr `parser:"'THEN' @@"`
 }
 type Func struct {
-	Name *QIdent `parser:"@@"`
-	Args []*Expr `parser:"'(' ( @@ ( ',' @@ )* )? ')'"`
+	Name     *QIdent `parser:"@@ '('"`
+	Distinct bool    `parser:"@'DISTINCT'?"`
+	Star     bool    `parser:"( @'*'"`
+	Args     []*Expr `parser:"| @@ ( ',' @@ )* )? ')'"`
+	Over     *Window `parser:"( 'OVER' '(' @@ ')' )?"`
+}
+type Window struct {
+	PartitionBy []*Expr      `parser:"( 'PARTITION' 'BY' @@ ( ',' @@ )* )?"`
+	OrderBy     []*OrderItem `parser:"( 'ORDER' 'BY' @@ ( ',' @@ )* )?"`
 }
 
 /* ---------- Build ---------- */
 
-var Parser = participle.MustBuild[Query](
-	participle.Lexer(SqlLex),
-	participle.Elide("WS", "LineComment"),
-	participle.CaseInsensitive(
-		"select", "from", "where", "qualify", "join", "left", "right", "full", "outer", "inner", "cross", "natural", "on", "using", "and", "or",
-	),
-)
+// unquoteIdent strips the delimiters of a quoted identifier, so that it
+// matches the column name in the schema exactly, and turns doubled
+// quote characters back into single ones.
+func unquoteIdent(t lexer.Token) (lexer.Token, error) {
+	open, close := t.Value[:1], t.Value[len(t.Value)-1:]
+	inner := t.Value[1 : len(t.Value)-1]
+	if open != "[" {
+		inner = strings.ReplaceAll(inner, open+open, open)
+	}
+	if inner == "" {
+		return t, participle.Errorf(t.Pos, "empty quoted identifier %s%s", open, close)
+	}
+	t.Value = inner
+	return t, nil
+}
+
+// requoteString turns a string quoted with double quotes, as Spark and
+// BigQuery allow, into the single-quoted form the rest of the parser
+// reads.
+func requoteString(t lexer.Token) (lexer.Token, error) {
+	if !strings.HasPrefix(t.Value, `"`) {
+		return t, nil
+	}
+	inner := strings.ReplaceAll(t.Value[1:len(t.Value)-1], `""`, `"`)
+	t.Value = "'" + strings.ReplaceAll(inner, "'", "''") + "'"
+	return t, nil
+}
+
+func build(lex lexer.Definition) (*participle.Parser[Query], error) {
+	return participle.Build[Query](
+		participle.Lexer(lex),
+		participle.Elide("WS", "LineComment", "BlockComment"),
+		participle.Map(unquoteIdent, "QuotedIdent"),
+		participle.Map(requoteString, "String"),
+		participle.CaseInsensitive("Keyword", "Ident"),
+		participle.UseLookahead(8),
+	)
+}
+
+// Parser parses the Generic dialect. To parse another dialect, use its
+// ParseString.
+var Parser = Generic.parser()
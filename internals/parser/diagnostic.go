@@ -0,0 +1,215 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/participle/v2"
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+// Diagnostic is an error located in the text of a query. The analysis
+// of a query locates its errors with At, at the predicate or the join
+// they come from, and Diagnose turns any error, parse errors included,
+// into a Diagnostic that can be reported with Report.
+type Diagnostic struct {
+	// Pos is where the error is in the query. Its Line is 0 when the
+	// error is not about any part of the query in particular.
+	Pos lexer.Position
+	Err error
+	// Message is the message of the error as Report shows it, which is
+	// that of Err when it is empty.
+	Message string
+	// Excerpt is the line of the query at Pos, with a caret under Pos on
+	// the line below.
+	Excerpt string
+	// Suggestions are the names that were likely meant, for an error
+	// about a name that is not known.
+	Suggestions []string
+}
+
+// Error returns the message of the error the Diagnostic locates, so
+// that locating an error does not change what it says.
+func (d *Diagnostic) Error() string {
+	return d.Err.Error()
+}
+
+func (d *Diagnostic) Unwrap() error {
+	return d.Err
+}
+
+// Report renders the Diagnostic for a reader, as in
+//
+//	orders.sql:2:7: unknown column "amout"
+//	  WHERE amout > 5
+//	        ^
+//	  did you mean "amount"?
+func (d *Diagnostic) Report() string {
+	var b strings.Builder
+	if d.Pos.Filename != "" {
+		b.WriteString(d.Pos.Filename + ":")
+	}
+	if d.Pos.Line > 0 {
+		fmt.Fprintf(&b, "%d:%d:", d.Pos.Line, d.Pos.Column)
+	}
+	if b.Len() > 0 {
+		b.WriteString(" ")
+	}
+	if d.Message != "" {
+		b.WriteString(d.Message)
+	} else {
+		b.WriteString(d.Err.Error())
+	}
+	if d.Excerpt != "" {
+		for _, line := range strings.Split(d.Excerpt, "\n") {
+			b.WriteString("\n  " + line)
+		}
+	}
+	if len(d.Suggestions) > 0 {
+		quoted := make([]string, 0, len(d.Suggestions))
+		for _, s := range d.Suggestions {
+			quoted = append(quoted, fmt.Sprintf("%q", s))
+		}
+		fmt.Fprintf(&b, "\n  did you mean %s?", strings.Join(quoted, " or "))
+	}
+	return b.String()
+}
+
+// At locates an error at the given position. An error that is located
+// already keeps its position, which is the more precise one, as that
+// of a predicate within a subquery is, while a Diagnostic without a
+// position, which only holds suggestions, takes the given one. The
+// Diagnostic is copied rather than changed, as it may be shared.
+func At(pos lexer.Position, err error) error {
+	if err == nil || pos.Line == 0 {
+		return err
+	}
+	var d *Diagnostic
+	if !errors.As(err, &d) {
+		return &Diagnostic{Pos: pos, Err: err}
+	}
+	if d.Pos.Line != 0 {
+		return err
+	}
+	located := *d
+	located.Pos = pos
+	if d == err {
+		return &located
+	}
+	return &Diagnostic{Pos: pos, Err: err, Suggestions: d.Suggestions}
+}
+
+// Diagnose turns an error from parsing or analysing a query into a
+// Diagnostic, with an excerpt of the source of the query where the
+// error is located. The message keeps the context the error was
+// wrapped in, such as the CTE it comes from.
+func Diagnose(source string, err error) *Diagnostic {
+	if err == nil {
+		return nil
+	}
+	out := &Diagnostic{Err: err, Message: err.Error()}
+	var located *Diagnostic
+	var unexpected *participle.UnexpectedTokenError
+	var perr participle.Error
+	switch {
+	case errors.As(err, &located):
+		out.Pos, out.Suggestions = located.Pos, located.Suggestions
+	case errors.As(err, &unexpected):
+		out.Pos, out.Message = unexpected.Unexpected.Pos, unexpectedMessage(unexpected)
+	case errors.As(err, &perr):
+		out.Pos, out.Message = perr.Position(), perr.Message()
+	}
+	out.Excerpt = excerpt(source, out.Pos)
+	return out
+}
+
+// unexpectedMessage words an error about an unexpected token, followed
+// by what was expected instead.
+func unexpectedMessage(e *participle.UnexpectedTokenError) string {
+	what := fmt.Sprintf("unexpected token %q", e.Unexpected.Value)
+	if e.Unexpected.EOF() {
+		what = "unexpected end of query"
+	}
+	msg := e.Message()
+	if i := strings.Index(msg, " (expected "); i >= 0 {
+		return what + msg[i:]
+	}
+	return what
+}
+
+// excerpt returns the line of the source at pos, with a caret under the
+// column of pos. Tabs before the column are kept, so the caret lines up.
+func excerpt(source string, pos lexer.Position) string {
+	lines := strings.Split(source, "\n")
+	if pos.Line < 1 || pos.Line > len(lines) {
+		return ""
+	}
+	line := strings.TrimRight(lines[pos.Line-1], "\r")
+	var caret strings.Builder
+	for i, r := range []rune(line) {
+		if i >= pos.Column-1 {
+			break
+		}
+		if r == '\t' {
+			caret.WriteRune('\t')
+		} else {
+			caret.WriteRune(' ')
+		}
+	}
+	return line + "\n" + caret.String() + "^"
+}
+
+// Suggest returns the candidates that are close enough to a name to be
+// what was meant by it, closest first. Case does not count, so a name
+// that only differs in case is the first to be suggested.
+func Suggest(name string, candidates []string) []string {
+	type scored struct {
+		name string
+		dist int
+	}
+	limit := max(1, len(name)/3)
+	var near []scored
+	seen := make(map[string]bool)
+	for _, c := range candidates {
+		if seen[c] || c == name {
+			continue
+		}
+		seen[c] = true
+		if d := distance(strings.ToLower(name), strings.ToLower(c)); d <= limit {
+			near = append(near, scored{c, d})
+		}
+	}
+	sort.SliceStable(near, func(i, j int) bool {
+		return near[i].dist < near[j].dist
+	})
+	var out []string
+	for _, c := range near[:min(len(near), 3)] {
+		out = append(out, c.name)
+	}
+	return out
+}
+
+// distance is the number of runes to insert, delete or replace to turn
+// one string into the other.
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,210 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/participle/v2"
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+// Dialect is the flavour of SQL a query is written in. It decides how
+// strings and identifiers are quoted, which words are reserved, whether
+// a SELECT may end in QUALIFY, and what its functions are called.
+type Dialect struct {
+	Name string
+	// StringQuotes are the characters a string literal may be quoted
+	// with, such as ' or ".
+	StringQuotes []string
+	// IdentQuotes are the opening and closing delimiters a quoted
+	// identifier may use, such as "" or [].
+	IdentQuotes []string
+	// Keywords are the words the dialect reserves on top of Keywords.
+	Keywords []string
+	// Qualify reserves QUALIFY, so that a SELECT may end in a QUALIFY
+	// clause that filters the rows on a window function.
+	Qualify bool
+	// TryCasts are the words the dialect has for a cast that gives NULL
+	// for a value it cannot convert, TRY_CAST or SAFE_CAST.
+	TryCasts []string
+	// Functions maps the upper-cased name of a function of the dialect
+	// onto the name of the function it stands for, as NVL stands for
+	// COALESCE, so that the IR only knows one name for each.
+	Functions map[string]string
+
+	once  sync.Once
+	built *participle.Parser[Query]
+	err   error
+}
+
+var (
+	// Generic accepts the quoting of most dialects at once, and is the
+	// dialect of Parser.
+	Generic = &Dialect{
+		Name:         "generic",
+		StringQuotes: []string{"'"},
+		IdentQuotes:  []string{`""`, "``", "[]"},
+		Keywords:     []string{"ilike"},
+		Qualify:      true,
+		TryCasts:     []string{"TRY_CAST", "SAFE_CAST"},
+	}
+	ANSI = &Dialect{
+		Name:         "ansi",
+		StringQuotes: []string{"'"},
+		IdentQuotes:  []string{`""`},
+	}
+	Postgres = &Dialect{
+		Name:         "postgres",
+		StringQuotes: []string{"'"},
+		IdentQuotes:  []string{`""`},
+		Keywords:     []string{"ilike"},
+	}
+	Databricks = &Dialect{
+		Name:         "databricks",
+		StringQuotes: []string{"'", `"`},
+		IdentQuotes:  []string{"``"},
+		Keywords:     []string{"ilike"},
+		Qualify:      true,
+		TryCasts:     []string{"TRY_CAST"},
+		Functions:    map[string]string{"NVL": "COALESCE", "IFNULL": "COALESCE", "MEAN": "AVG"},
+	}
+	Snowflake = &Dialect{
+		Name:         "snowflake",
+		StringQuotes: []string{"'"},
+		IdentQuotes:  []string{`""`},
+		Keywords:     []string{"ilike"},
+		Qualify:      true,
+		TryCasts:     []string{"TRY_CAST"},
+		Functions:    map[string]string{"NVL": "COALESCE", "IFNULL": "COALESCE"},
+	}
+	BigQuery = &Dialect{
+		Name:         "bigquery",
+		StringQuotes: []string{"'", `"`},
+		IdentQuotes:  []string{"``"},
+		Qualify:      true,
+		TryCasts:     []string{"SAFE_CAST"},
+		Functions:    map[string]string{"IFNULL": "COALESCE"},
+	}
+	DuckDB = &Dialect{
+		Name:         "duckdb",
+		StringQuotes: []string{"'"},
+		IdentQuotes:  []string{`""`},
+		Keywords:     []string{"ilike"},
+		Qualify:      true,
+		TryCasts:     []string{"TRY_CAST"},
+		Functions:    map[string]string{"IFNULL": "COALESCE", "MEAN": "AVG"},
+	}
+)
+
+// dialects holds the dialects by the names LookupDialect knows them by.
+var dialects = map[string]*Dialect{
+	"generic":    Generic,
+	"ansi":       ANSI,
+	"postgres":   Postgres,
+	"postgresql": Postgres,
+	"databricks": Databricks,
+	"spark":      Databricks,
+	"snowflake":  Snowflake,
+	"bigquery":   BigQuery,
+	"duckdb":     DuckDB,
+}
+
+// LookupDialect returns the dialect of the given name, which is matched
+// case-insensitively. Spark is read as Databricks.
+func LookupDialect(name string) (*Dialect, error) {
+	d, ok := dialects[strings.ToLower(name)]
+	if !ok {
+		names := make([]string, 0, len(dialects))
+		for n := range dialects {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown dialect %q, expected one of %s", name, strings.Join(names, ", "))
+	}
+	return d, nil
+}
+
+// ParseString parses a query written in the dialect. The functions the
+// dialect has other names for are renamed to the names the IR knows,
+// and a TRY_CAST or SAFE_CAST the dialect does not have is an error.
+func (d *Dialect) ParseString(filename, sql string) (*Query, error) {
+	p, err := d.build()
+	if err != nil {
+		return nil, err
+	}
+	q, err := p.ParseString(filename, sql)
+	if err != nil {
+		return nil, err
+	}
+	var bad *Cast
+	w := walker{fn: d.rename, deep: true, cast: func(c *Cast) {
+		if bad == nil && c.Try != "" && !slices.Contains(d.TryCasts, strings.ToUpper(c.Try)) {
+			bad = c
+		}
+	}}
+	w.query(q)
+	if bad != nil {
+		return nil, participle.Errorf(bad.Pos, "%s is not supported in the %s dialect", strings.ToUpper(bad.Try), d.Name)
+	}
+	return q, nil
+}
+
+// build builds the parser of the dialect the first time it is needed.
+func (d *Dialect) build() (*participle.Parser[Query], error) {
+	d.once.Do(func() {
+		d.built, d.err = build(d.lexer())
+		if d.err != nil {
+			d.err = fmt.Errorf("dialect %s: %w", d.Name, d.err)
+		}
+	})
+	return d.built, d.err
+}
+
+// parser returns the parser of the dialect, and panics if it cannot be
+// built.
+func (d *Dialect) parser() *participle.Parser[Query] {
+	p, err := d.build()
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func (d *Dialect) lexer() *lexer.StatefulDefinition {
+	strs := make([]string, 0, len(d.StringQuotes))
+	for _, q := range d.StringQuotes {
+		strs = append(strs, quotedPattern(q, q))
+	}
+	idents := make([]string, 0, len(d.IdentQuotes))
+	for _, q := range d.IdentQuotes {
+		idents = append(idents, quotedPattern(q[:1], q[1:]))
+	}
+	keywords := append(append([]string{}, Keywords...), d.Keywords...)
+	if d.Qualify {
+		keywords = append(keywords, "qualify")
+	}
+	return newLexer(strings.Join(strs, "|"), strings.Join(idents, "|"), keywords)
+}
+
+// quotedPattern matches text between the given delimiters. When they are
+// the same character, a doubled delimiter stands for the character itself.
+func quotedPattern(open, close string) string {
+	o, c := regexp.QuoteMeta(open), regexp.QuoteMeta(close)
+	if open == close {
+		return o + `([^` + c + `]|` + c + c + `)*` + c
+	}
+	return o + `[^` + c + `]*` + c
+}
+
+func (d *Dialect) rename(f *Func) {
+	if f.Name == nil || len(f.Name.Parts) != 1 {
+		return
+	}
+	if name, ok := d.Functions[strings.ToUpper(f.Name.Parts[0])]; ok {
+		f.Name.Parts[0] = name
+	}
+}
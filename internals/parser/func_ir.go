@@ -0,0 +1,165 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+)
+
+// FuncIR is a function call, with its upper-cased name and its
+// arguments in order. A call inside a call, as in UPPER(TRIM(name)),
+// is kept among the arguments of the outer one. Star marks the * of
+// COUNT(*). A window function, such as ROW_NUMBER() OVER (...), holds
+// its window in Window.
+type FuncIR struct {
+	Name     string
+	Distinct bool
+	Star     bool
+	Args     []ArgIR
+	Window   *WindowIR
+}
+
+// WindowIR is the window of a window function, with the keys it is
+// partitioned by and the keys the rows of a partition are ordered by.
+// Keys that are not a column are kept as an argument would be in
+// PartitionBy, and left out of OrderBy.
+type WindowIR struct {
+	PartitionBy []ArgIR
+	OrderBy     []OrderIR
+}
+
+// ArgIR is an argument of a function call, which is a column, a literal
+// or a function call. Any other expression, such as arithmetic, is kept
+// as text in Expr.
+type ArgIR struct {
+	Column  *ColumnIR
+	Literal RightIR
+	Func    *FuncIR
+	Expr    string
+}
+
+// toIR converts a function call into its IR, along with the calls in
+// its arguments.
+func (f *Func) toIR() *FuncIR {
+	out := &FuncIR{
+		Name:     strings.ToUpper(strings.Join(f.Name.Parts, ".")),
+		Distinct: f.Distinct,
+		Star:     f.Star,
+		Args:     make([]ArgIR, 0, len(f.Args)),
+	}
+	for _, arg := range f.Args {
+		out.Args = append(out.Args, argToIR(arg))
+	}
+	if f.Over != nil {
+		out.Window = &WindowIR{
+			PartitionBy: make([]ArgIR, 0, len(f.Over.PartitionBy)),
+			OrderBy:     orderToIR(f.Over.OrderBy),
+		}
+		for _, e := range f.Over.PartitionBy {
+			out.Window.PartitionBy = append(out.Window.PartitionBy, argToIR(e))
+		}
+	}
+	return out
+}
+
+func argToIR(e *Expr) ArgIR {
+	arith := e.arith()
+	p, _ := arith.primary().uncast()
+	switch {
+	case p == nil:
+		if arith == nil {
+			return ArgIR{Expr: "(condition)"}
+		}
+		return ArgIR{Expr: arithAtom(arith)}
+	case isLiteral(p):
+		return ArgIR{Literal: RightIR(primaryAtom(p))}
+	case p.QIdent != nil:
+		return ArgIR{Column: p.QIdent.column()}
+	case p.Func != nil:
+		return ArgIR{Func: p.Func.toIR()}
+	default:
+		return ArgIR{Expr: arithAtom(arith)}
+	}
+}
+
+// String renders the call the way it would be written in a query, as in
+// COALESCE(a, 0).
+func (f *FuncIR) String() string {
+	args := make([]string, 0, len(f.Args))
+	for _, a := range f.Args {
+		args = append(args, a.String())
+	}
+	inner := strings.Join(args, ", ")
+	switch {
+	case f.Star:
+		inner = "*"
+	case f.Distinct:
+		inner = "DISTINCT " + inner
+	}
+	call := f.Name + "(" + inner + ")"
+	if f.Window != nil {
+		call += " OVER (" + f.Window.String() + ")"
+	}
+	return call
+}
+
+func (w *WindowIR) String() string {
+	var parts []string
+	if len(w.PartitionBy) > 0 {
+		keys := make([]string, 0, len(w.PartitionBy))
+		for _, k := range w.PartitionBy {
+			keys = append(keys, k.String())
+		}
+		parts = append(parts, "PARTITION BY "+strings.Join(keys, ", "))
+	}
+	if len(w.OrderBy) > 0 {
+		keys := make([]string, 0, len(w.OrderBy))
+		for _, k := range w.OrderBy {
+			key := strconv.Itoa(k.Position)
+			if k.Column != nil {
+				key = k.Column.String()
+			}
+			if k.Desc {
+				key += " DESC"
+			}
+			keys = append(keys, key)
+		}
+		parts = append(parts, "ORDER BY "+strings.Join(keys, ", "))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (a ArgIR) String() string {
+	switch {
+	case a.Column != nil:
+		return a.Column.String()
+	case a.Literal != "":
+		return string(a.Literal)
+	case a.Func != nil:
+		return a.Func.String()
+	default:
+		return a.Expr
+	}
+}
+
+// Columns returns the columns the call reads, including those of the
+// calls in its arguments, in the order they appear.
+func (f *FuncIR) Columns() []ColumnIR {
+	var out []ColumnIR
+	for _, a := range f.Args {
+		switch {
+		case a.Column != nil:
+			out = append(out, *a.Column)
+		case a.Func != nil:
+			out = append(out, a.Func.Columns()...)
+		}
+	}
+	return out
+}
+
+// callIR returns the IR of a Primary that is a function call, or nil.
+func (p *Primary) callIR() *FuncIR {
+	if p == nil || p.Func == nil {
+		return nil
+	}
+	return p.Func.toIR()
+}
@@ -0,0 +1,83 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// aggregates holds the aggregate functions that conditions can be
+// generated for.
+var aggregates = map[string]bool{
+	"COUNT": true,
+	"SUM":   true,
+	"AVG":   true,
+	"MIN":   true,
+	"MAX":   true,
+}
+
+// aggregate returns the upper-cased name of an aggregate function call,
+// or an empty string for any other function, and for window functions.
+func (f *Func) aggregate() string {
+	// An aggregate over a window is computed for every row, not a group
+	if len(f.Name.Parts) != 1 || f.Over != nil {
+		return ""
+	}
+	name := strings.ToUpper(f.Name.Parts[0])
+	if !aggregates[name] {
+		return ""
+	}
+	return name
+}
+
+// aggregateToIR converts a comparison on an aggregate, such as
+// COUNT(*) >= 3, into a ConditionsIR on the argument of the aggregate.
+// Only aggregates over a single column, and COUNT(*), are supported.
+func aggregateToIR(f *Func, op string, right *Primary, negated bool) ConditionsIR {
+	out := ConditionsIR{
+		Op:        OpIR(op),
+		Right:     RightIR(primaryAtom(right)),
+		Negated:   negated,
+		Aggregate: f.aggregate(),
+	}
+	switch {
+	case f.Distinct:
+		out.Unsupported = fmt.Sprintf("%s(DISTINCT ...) is not supported", out.Aggregate)
+	case f.Star:
+		out.Left = "*"
+	case len(f.Args) == 1 && f.Args[0].arith().primary() != nil && f.Args[0].arith().primary().QIdent != nil:
+		out.Left = LeftIR(primaryAtom(f.Args[0].arith().primary()))
+	default:
+		out.Unsupported = fmt.Sprintf("%s is only supported over a single column", out.Aggregate)
+	}
+	if !isLiteral(right) {
+		out.Unsupported = fmt.Sprintf("%s can only be compared with a literal", out.Aggregate)
+	}
+	return out
+}
+
+// GetGroupBy returns the columns a SELECT is grouped by. A position such
+// as the 1 in GROUP BY 1 stands for the column selected in that place.
+func (q *SelectCore) GetGroupBy() ([]ColumnIR, error) {
+	out := make([]ColumnIR, 0, len(q.GroupBy))
+	for _, e := range q.GroupBy {
+		p := e.arith().primary()
+		switch {
+		case p != nil && p.QIdent != nil:
+			out = append(out, *p.QIdent.column())
+		case p != nil && p.Num != nil:
+			n, err := strconv.Atoi(*p.Num)
+			if err != nil || n < 1 || n > len(q.Select.Items) {
+				return nil, fmt.Errorf("GROUP BY %s is not a position in the select list", *p.Num)
+			}
+			col, ok := q.Select.Items[n-1].Column()
+			if !ok {
+				return nil, fmt.Errorf("GROUP BY %d: only columns are supported", n)
+			}
+			out = append(out, col)
+		default:
+			return nil, fmt.Errorf("GROUP BY %s: only columns are supported", arithAtom(e.arith()))
+		}
+	}
+	return out, nil
+}
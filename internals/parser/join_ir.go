@@ -1,5 +1,11 @@
 package parser
 
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
 // JoinKind represents the type of SQL JOIN operation. The values correspond
 // to the different JOIN syntax variants that can appear in a SELECT
 // statement.
@@ -39,14 +45,50 @@ const (
 )
 
 // JoinIR is the intermediate representation of a JOIN clause.
-// It contains the join kind, the name of the joined table, and the
-// conditions used to match rows.
+// It contains the join kind, the fully qualified name of the joined
+// table, its alias, and the conditions used to match rows. Using holds
+// the columns of a USING clause, and Keys the pairs of columns that a
+// USING or NATURAL join matches rows on. Condition is nil for a join
+// without an ON clause.
 type JoinIR struct {
 	Kind      string
 	Table     string
+	Alias     string
+	Using     []string
+	Keys      []JoinKeyIR
 	Condition []ConditionsIR
 }
 
+// JoinKeyIR is a pair of columns that a join matches rows on, with the
+// column of the tables joined before on the Left, and the column of the
+// joined table on the Right. Both are qualified with the name their
+// table is referred to by, when it is known.
+type JoinKeyIR struct {
+	Left  LeftIR
+	Right LeftIR
+}
+
+// Schemas maps the name of a table onto the names of its columns, which
+// the keys of USING and NATURAL joins are resolved against.
+type Schemas map[string][]string
+
+// columns returns the columns of a table, which may be looked up by its
+// name alone when it is schema-qualified in the query.
+func (s Schemas) columns(table string) ([]string, bool) {
+	for name, cols := range s {
+		if strings.EqualFold(name, table) {
+			return cols, true
+		}
+	}
+	parts := strings.Split(table, ".")
+	for name, cols := range s {
+		if strings.EqualFold(name, parts[len(parts)-1]) {
+			return cols, true
+		}
+	}
+	return nil, false
+}
+
 // GetKind returns the JoinKind corresponding to the flags set on the
 // JoinType value. It interprets the presence of Cross, Nat, Left, Right,
 // Full, and Inner flags in the following order:
@@ -94,23 +136,207 @@ func (jt *JoinType) GetKind() JoinKind {
 }
 
 // GetJoin converts a JoinClause into its intermediate representation
-// (JoinIR). It extracts the kind, the table name, and the ON
-// condition expressed as a ConditionsIR.
+// (JoinIR). It extracts the kind, the table name and alias, the USING
+// columns, and the ON condition expressed as a ConditionsIR. A derived
+// table is named by its alias. The keys of a USING join are left
+// unqualified on the left, as the tables joined before are not known
+// here.
 func (j *JoinClause) GetJoin() JoinIR {
-	return JoinIR{
-		Kind:      string(j.Type.GetKind()),
-		Table:     j.Table.Parts[0],
-		Condition: j.On.ToIR(),
+	out := JoinIR{
+		Kind:  string(j.Type.GetKind()),
+		Using: j.Using,
+	}
+	if j.Alias != nil {
+		out.Alias = *j.Alias
+	}
+	if j.Table != nil {
+		out.Table = strings.Join(j.Table.Parts, ".")
+	} else {
+		out.Table = out.Alias
+	}
+	if j.On != nil {
+		out.Condition = j.On.ToIR()
 	}
+	for _, col := range j.Using {
+		out.Keys = append(out.Keys, JoinKeyIR{Left: LeftIR(col), Right: qualify(out.refName(), col)})
+	}
+	return out
+}
+
+// refName is the name the joined table is referred to by in the query.
+func (j JoinIR) refName() string {
+	if j.Alias != "" {
+		return j.Alias
+	}
+	return j.Table
+}
+
+func qualify(table, column string) LeftIR {
+	return LeftIR(table + "." + column)
 }
 
 // GetJoins returns a slice of JoinIR objects representing all JOIN
-// clauses in the Query. It iterates over the Query's Joins field,
-// converting each to JoinIR via JoinClause.GetJoin.
-func (q *Query) GetJoins() []JoinIR {
+// clauses in a SELECT. It iterates over its Joins field, converting
+// each to JoinIR via JoinClause.GetJoin. The left key of a USING join
+// is qualified when a single table comes before it, and NATURAL joins
+// get no keys, as they depend on the schemas; see ResolveJoins. The
+// tables of an implicit join, as in FROM a, b, are inner joins, with
+// the equalities of the WHERE clause that join them as their condition.
+// On a compound Query it covers the first arm.
+func (q *SelectCore) GetJoins() []JoinIR {
+	out, _ := q.joins(nil)
+	return out
+}
+
+// ResolveJoins returns the joins of a SELECT as GetJoins does, with the
+// keys of the USING and NATURAL joins resolved against the schemas. The
+// left key of a join is on the first table before it that has the
+// column, and a NATURAL join matches every column of the joined table
+// that one of the tables before it has as well.
+func (q *SelectCore) ResolveJoins(schemas Schemas) ([]JoinIR, error) {
+	return q.joins(schemas)
+}
+
+// joinedTable is a table of the FROM or JOIN clauses, with its full name
+// and the name it is referred to by.
+type joinedTable struct {
+	name, table string
+}
+
+func newJoinedTable(table *QIdent, alias *string) joinedTable {
+	var t joinedTable
+	if table != nil {
+		t.table = strings.Join(table.Parts, ".")
+	}
+	t.name = t.table
+	if alias != nil {
+		t.name = *alias
+	}
+	return t
+}
+
+// qualifies reports whether the qualifier of a column reference, such as
+// the o of o.id, names the table.
+func (t joinedTable) qualifies(col *ColumnIR) bool {
+	qualifier := col.Table
+	if qualifier == "" {
+		return false
+	}
+	if strings.EqualFold(t.name, qualifier) || strings.EqualFold(t.table, qualifier) {
+		return true
+	}
+	parts := strings.Split(t.table, ".")
+	return t.name == t.table && strings.EqualFold(parts[len(parts)-1], qualifier)
+}
+
+// joinPredicates returns the equalities between columns in the WHERE
+// clause that join a table of an implicit join to one of the tables
+// before it, as the a.id = b.a_id of FROM a, b WHERE a.id = b.a_id.
+func (q *SelectCore) joinPredicates(t joinedTable, before []joinedTable) []ConditionsIR {
+	if q.Where == nil {
+		return nil
+	}
+	terms := []BoolIR{q.Where.Tree()}
+	if and, ok := terms[0].(*AndIR); ok {
+		terms = and.Terms
+	}
+	var out []ConditionsIR
+	for _, term := range terms {
+		p, ok := term.(*PredIR)
+		if !ok || p.Other == nil || p.Op != "=" || p.Negated {
+			continue
+		}
+		left, right := p.Column, p.Other
+		for _, other := range before {
+			if t.qualifies(left) && other.qualifies(right) || t.qualifies(right) && other.qualifies(left) {
+				out = append(out, p.cond)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// joins builds the joins of a SELECT, resolving their keys against the
+// schemas when there are any. The tables of an implicit join come
+// first, with the equalities that join them taken from the WHERE clause.
+func (q *SelectCore) joins(schemas Schemas) ([]JoinIR, error) {
+	var before []joinedTable
 	out := make([]JoinIR, 0, len(q.Joins))
+	if q.From != nil {
+		before = append(before, newJoinedTable(q.From.Table, q.From.Alias))
+		for _, t := range q.From.Implicit {
+			joined := newJoinedTable(t.Table, t.Alias)
+			ir := JoinIR{Kind: string(Inner), Table: joined.table}
+			if t.Alias != nil {
+				ir.Alias = *t.Alias
+			}
+			if t.Table == nil {
+				ir.Table = ir.Alias
+			}
+			ir.Condition = q.joinPredicates(joined, before)
+			out = append(out, ir)
+			before = append(before, joined)
+		}
+	}
+	// leftKey finds the table before the join that has the column
+	leftKey := func(col string) (LeftIR, bool) {
+		for _, rel := range before {
+			cols, ok := schemas.columns(rel.table)
+			if ok && slices.ContainsFunc(cols, func(c string) bool { return strings.EqualFold(c, col) }) {
+				return qualify(rel.name, col), true
+			}
+		}
+		if len(before) == 1 {
+			if _, known := schemas.columns(before[0].table); !known {
+				return qualify(before[0].name, col), true
+			}
+		}
+		return LeftIR(col), schemas == nil
+	}
+
 	for _, j := range q.Joins {
-		out = append(out, j.GetJoin())
+		ir := j.GetJoin()
+		ir.Keys = nil
+		cols := ir.Using
+		if j.Type != nil && j.Type.Nat && schemas != nil {
+			right, ok := schemas.columns(ir.Table)
+			if !ok {
+				return nil, At(j.Pos, fmt.Errorf("NATURAL JOIN %s: the columns of %s are not known", ir.Table, ir.Table))
+			}
+			cols = nil
+			for _, col := range right {
+				if _, ok := leftKey(col); ok {
+					cols = append(cols, col)
+				}
+			}
+		}
+		for _, col := range cols {
+			left, ok := leftKey(col)
+			if !ok {
+				var known []string
+				for _, t := range before {
+					cols, _ := schemas.columns(t.table)
+					known = append(known, cols...)
+				}
+				return nil, &Diagnostic{
+					Pos:         j.Pos,
+					Err:         fmt.Errorf("USING (%s): no table before %s has the column", col, ir.Table),
+					Suggestions: Suggest(col, known),
+				}
+			}
+			if right, known := schemas.columns(ir.Table); known &&
+				!slices.ContainsFunc(right, func(c string) bool { return strings.EqualFold(c, col) }) {
+				return nil, &Diagnostic{
+					Pos:         j.Pos,
+					Err:         fmt.Errorf("USING (%s): %s has no such column", col, ir.Table),
+					Suggestions: Suggest(col, right),
+				}
+			}
+			ir.Keys = append(ir.Keys, JoinKeyIR{Left: left, Right: qualify(ir.refName(), col)})
+		}
+		out = append(out, ir)
+		before = append(before, newJoinedTable(j.Table, j.Alias))
 	}
-	return out
+	return out, nil
 }
@@ -0,0 +1,93 @@
+package parser
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ColumnIR is a reference to a column, split into the table or alias it
+// is qualified with, if any, and the name of the column.
+type ColumnIR struct {
+	Table string
+	Name  string
+}
+
+func (c ColumnIR) String() string {
+	if c.Table == "" {
+		return c.Name
+	}
+	return c.Table + "." + c.Name
+}
+
+// column returns the column an identifier refers to. Only its last part
+// names the column, so a quoted "a.b" is a column by that name.
+func (q *QIdent) column() *ColumnIR {
+	n := len(q.Parts)
+	return &ColumnIR{Table: strings.Join(q.Parts[:n-1], "."), Name: q.Parts[n-1]}
+}
+
+// LiteralKind is the type of a literal.
+type LiteralKind string
+
+const (
+	IntLiteral       LiteralKind = "int"
+	FloatLiteral     LiteralKind = "float"
+	StringLiteral    LiteralKind = "string"
+	TimestampLiteral LiteralKind = "timestamp"
+	BoolLiteral      LiteralKind = "bool"
+)
+
+// LiteralIR is a literal with its value parsed according to its Kind. A
+// string literal that reads as a date or a timestamp has the kind
+// timestamp, and keeps its text in Str as well, since it may just as
+// well be compared with a string column.
+type LiteralIR struct {
+	Kind  LiteralKind
+	Int   int
+	Float float64
+	Str   string
+	Time  time.Time
+	Bool  bool
+}
+
+// literalIR parses a literal as written in a query. It reports false
+// for anything that is not a literal, such as a column.
+func literalIR(raw RightIR) (LiteralIR, bool) {
+	s := string(raw)
+	switch {
+	case strings.EqualFold(s, "true") || strings.EqualFold(s, "false"):
+		return LiteralIR{Kind: BoolLiteral, Bool: strings.EqualFold(s, "true")}, true
+	case len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'':
+		str := strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+		for _, layout := range []string{time.RFC3339, time.DateOnly} {
+			if t, err := time.Parse(layout, str); err == nil {
+				return LiteralIR{Kind: TimestampLiteral, Str: str, Time: t}, true
+			}
+		}
+		return LiteralIR{Kind: StringLiteral, Str: str}, true
+	}
+	// An int is parsed as one, as a float only holds 53 bits of it
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return LiteralIR{Kind: IntLiteral, Int: int(n), Float: float64(n)}, true
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return LiteralIR{}, false
+	}
+	if f == math.Trunc(f) && math.Abs(f) < math.MaxInt64 {
+		return LiteralIR{Kind: IntLiteral, Int: int(f), Float: f}, true
+	}
+	return LiteralIR{Kind: FloatLiteral, Float: f}, true
+}
+
+// isBool reports whether a Primary is the literal TRUE or FALSE, which
+// are lexed as identifiers.
+func isBool(p *Primary) bool {
+	if p == nil || p.QIdent == nil || len(p.QIdent.Parts) != 1 {
+		return false
+	}
+	name := p.QIdent.Parts[0]
+	return strings.EqualFold(name, "true") || strings.EqualFold(name, "false")
+}
@@ -0,0 +1,53 @@
+package parser
+
+import "strconv"
+
+// OrderIR is a key of an ORDER BY clause. A key either names a column,
+// which may be an alias from the select list, or refers to a place in
+// the select list by Position, which counts from 1.
+type OrderIR struct {
+	Column   *ColumnIR
+	Position int
+	Desc     bool
+}
+
+// GetOrderBy returns the keys of the ORDER BY clause of a query. Keys
+// that are neither a column nor a position, such as expressions, are
+// left out.
+func (q *Query) GetOrderBy() []OrderIR {
+	return orderToIR(q.OrderBy)
+}
+
+func orderToIR(items []*OrderItem) []OrderIR {
+	out := make([]OrderIR, 0, len(items))
+	for _, item := range items {
+		p := item.Expr.arith().primary()
+		switch {
+		case p != nil && p.QIdent != nil:
+			out = append(out, OrderIR{Column: p.QIdent.column(), Desc: item.Desc})
+		case p != nil && p.Num != nil:
+			n, err := strconv.Atoi(*p.Num)
+			if err == nil && n >= 1 {
+				out = append(out, OrderIR{Position: n, Desc: item.Desc})
+			}
+		}
+	}
+	return out
+}
+
+// MinRows returns the number of rows the result of a query needs for
+// its LIMIT and OFFSET to return a full page, or 0 for a query with
+// neither.
+func (q *Query) MinRows() int {
+	switch {
+	case q.Limit != nil && q.Offset != nil:
+		return *q.Limit + *q.Offset
+	case q.Limit != nil:
+		return *q.Limit
+	case q.Offset != nil:
+		// Something has to be left after the rows that are skipped
+		return *q.Offset + 1
+	default:
+		return 0
+	}
+}
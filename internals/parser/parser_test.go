@@ -1,8 +1,11 @@
 package parser_test
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/alecthomas/participle/v2/lexer"
 	"github.com/phdah/sql-tdg/internals/parser"
 	"github.com/stretchr/testify/require"
 )
@@ -15,9 +18,9 @@ func TestParse_QuaryParsing(t *testing.T) {
 		JOIN u ON t.x = u.p AND t.x = u.r
 		CROSS JOIN t ON t.a > t.l
 		NATURAL JOIN t ON t.a > t.l
-		WHERE x > 5 OR y = 10 AND t AND x = 10 AND t = "2025-06-19"
+		WHERE x > 5 OR y = 10 AND t AND x = 10 AND t = '2025-06-19'
+		QUALIFY row_number() OVER (PARTITION BY x ORDER BY y DESC) = 1
 	`
-	// QUALIFY row_number() = 1
 	q, err := parser.Parser.ParseString("", query)
 	if err != nil {
 		t.Fatalf("Failed parsing query:\n%s, err:\n%e", query, err)
@@ -26,25 +29,25 @@ func TestParse_QuaryParsing(t *testing.T) {
 		{
 			Kind:      "LEFT",
 			Table:     "u",
-			Condition: []parser.ConditionsIR{{Left: "t.x", Op: "=", Right: "u.p"}},
+			Condition: []parser.ConditionsIR{{Left: "t.x", Op: "=", Right: "u.p", RightColumn: true}},
 		},
 		{
 			Kind:  "INNER",
 			Table: "u",
 			Condition: []parser.ConditionsIR{
-				{Left: "t.x", Op: "=", Right: "u.p"},
-				{Left: "t.x", Op: "=", Right: "u.r"},
+				{Left: "t.x", Op: "=", Right: "u.p", RightColumn: true},
+				{Left: "t.x", Op: "=", Right: "u.r", RightColumn: true},
 			},
 		},
 		{
 			Kind:      "CROSS",
 			Table:     "t",
-			Condition: []parser.ConditionsIR{{Left: "t.a", Op: ">", Right: "t.l"}},
+			Condition: []parser.ConditionsIR{{Left: "t.a", Op: ">", Right: "t.l", RightColumn: true}},
 		},
 		{
 			Kind:      "NATURAL INNER",
 			Table:     "t",
-			Condition: []parser.ConditionsIR{{Left: "t.a", Op: ">", Right: "t.l"}},
+			Condition: []parser.ConditionsIR{{Left: "t.a", Op: ">", Right: "t.l", RightColumn: true}},
 		},
 	}
 	wantConditions := []parser.ConditionsIR{
@@ -71,7 +74,20 @@ func TestParse_QuaryParsing(t *testing.T) {
 		{
 			Left:  "t",
 			Op:    "=",
-			Right: `"2025-06-19"`,
+			Right: `'2025-06-19'`,
+		},
+		{
+			Left:  "ROW_NUMBER() OVER (PARTITION BY x ORDER BY y DESC)",
+			Op:    "=",
+			Right: "1",
+			Func: &parser.FuncIR{
+				Name: "ROW_NUMBER",
+				Args: []parser.ArgIR{},
+				Window: &parser.WindowIR{
+					PartitionBy: []parser.ArgIR{{Column: &parser.ColumnIR{Name: "x"}}},
+					OrderBy:     []parser.OrderIR{{Column: &parser.ColumnIR{Name: "y"}, Desc: true}},
+				},
+			},
 		},
 	}
 	gotJoins := q.GetJoins()
@@ -81,3 +97,1188 @@ func TestParse_QuaryParsing(t *testing.T) {
 	r.Equal(gotJoins, wantJoins)
 	r.Equal(gotConditions, wantConditions)
 }
+
+func TestParse_NotParsing(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []parser.ConditionsIR
+	}{
+		{
+			name:  "negated comparison",
+			query: "SELECT a FROM t WHERE NOT a > 10",
+			want: []parser.ConditionsIR{
+				{Left: "a", Op: ">", Right: "10", Negated: true},
+			},
+		},
+		{
+			name:  "negated boolean column",
+			query: "SELECT a FROM t WHERE NOT is_active",
+			want: []parser.ConditionsIR{
+				{Left: "is_active", Op: "bool", Right: "true", Negated: true},
+			},
+		},
+		{
+			name:  "negated parenthesised expression",
+			query: "SELECT a FROM t WHERE NOT (a > 10 AND NOT b = 5) AND c < 3",
+			want: []parser.ConditionsIR{
+				{Left: "a", Op: ">", Right: "10", Negated: true},
+				{Left: "b", Op: "=", Right: "5", Negated: false},
+				{Left: "c", Op: "<", Right: "3", Negated: false},
+			},
+		},
+		{
+			name:  "double negation",
+			query: "SELECT a FROM t WHERE NOT NOT a = 1",
+			want: []parser.ConditionsIR{
+				{Left: "a", Op: "=", Right: "1", Negated: false},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			q, err := parser.Parser.ParseString("", tt.query)
+			r.NoError(err)
+			r.Equal(tt.want, q.GetConditions())
+		})
+	}
+}
+
+func TestParse_InParsing(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []parser.ConditionsIR
+	}{
+		{
+			name:  "in list",
+			query: "SELECT a FROM t WHERE a IN (1, 2, 3)",
+			want: []parser.ConditionsIR{
+				{Left: "a", Op: "IN", Values: []parser.RightIR{"1", "2", "3"}},
+			},
+		},
+		{
+			name:  "not in list",
+			query: "SELECT a FROM t WHERE country NOT IN ('SE', 'NO') AND a = 1",
+			want: []parser.ConditionsIR{
+				{Left: "country", Op: "IN", Values: []parser.RightIR{"'SE'", "'NO'"}, Negated: true},
+				{Left: "a", Op: "=", Right: "1"},
+			},
+		},
+		{
+			name:  "negated not in list",
+			query: "SELECT a FROM t WHERE NOT a NOT IN (1)",
+			want: []parser.ConditionsIR{
+				{Left: "a", Op: "IN", Values: []parser.RightIR{"1"}, Negated: false},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			q, err := parser.Parser.ParseString("", tt.query)
+			r.NoError(err)
+			r.Equal(tt.want, q.GetConditions())
+		})
+	}
+}
+
+func TestParse_BetweenParsing(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []parser.ConditionsIR
+	}{
+		{
+			name:  "between followed by a conjunction",
+			query: "SELECT a FROM t WHERE a BETWEEN 10 AND 20 AND b = 1",
+			want: []parser.ConditionsIR{
+				{Left: "a", Op: "BETWEEN", Values: []parser.RightIR{"10", "20"}},
+				{Left: "b", Op: "=", Right: "1"},
+			},
+		},
+		{
+			name:  "not between dates",
+			query: "SELECT a FROM t WHERE event_ts NOT BETWEEN '2024-01-01' AND '2024-01-31'",
+			want: []parser.ConditionsIR{
+				{Left: "event_ts", Op: "BETWEEN", Values: []parser.RightIR{"'2024-01-01'", "'2024-01-31'"}, Negated: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			q, err := parser.Parser.ParseString("", tt.query)
+			r.NoError(err)
+			r.Equal(tt.want, q.GetConditions())
+		})
+	}
+}
+
+func TestParse_IsNullParsing(t *testing.T) {
+	query := "SELECT a FROM t WHERE deleted_at IS NULL AND updated_at IS NOT NULL AND NOT a IS NULL"
+	want := []parser.ConditionsIR{
+		{Left: "deleted_at", Op: "IS NULL"},
+		{Left: "updated_at", Op: "IS NULL", Negated: true},
+		{Left: "a", Op: "IS NULL", Negated: true},
+	}
+	r := require.New(t)
+	q, err := parser.Parser.ParseString("", query)
+	r.NoError(err)
+	r.Equal(want, q.GetConditions())
+}
+
+func TestParse_LikeParsing(t *testing.T) {
+	query := "SELECT a FROM t WHERE email LIKE '%@corp.com' AND name NOT ILIKE 'admin%'"
+	want := []parser.ConditionsIR{
+		{Left: "email", Op: "LIKE", Right: "'%@corp.com'"},
+		{Left: "name", Op: "ILIKE", Right: "'admin%'", Negated: true},
+	}
+	r := require.New(t)
+	q, err := parser.Parser.ParseString("", query)
+	r.NoError(err)
+	r.Equal(want, q.GetConditions())
+}
+
+func TestParse_NumberParsing(t *testing.T) {
+	query := "SELECT a FROM t WHERE a > -5 AND b < 9.99 AND c = 1e6 AND d >= +.5 AND e IN (-1, 2.5E-3)"
+	want := []parser.ConditionsIR{
+		{Left: "a", Op: ">", Right: "-5"},
+		{Left: "b", Op: "<", Right: "9.99"},
+		{Left: "c", Op: "=", Right: "1e6"},
+		{Left: "d", Op: ">=", Right: "+.5"},
+		{Left: "e", Op: "IN", Values: []parser.RightIR{"-1", "2.5E-3"}},
+	}
+	r := require.New(t)
+	q, err := parser.Parser.ParseString("", query)
+	r.NoError(err)
+	r.Equal(want, q.GetConditions())
+}
+
+func TestParse_ArithParsing(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []parser.ConditionsIR
+	}{
+		{
+			name:  "addition",
+			query: "SELECT a FROM t WHERE a + 5 > 20",
+			want:  []parser.ConditionsIR{{Left: "a", Op: ">", Right: "15"}},
+		},
+		{
+			name:  "precedence and parentheses",
+			query: "SELECT a FROM t WHERE 2 * (a - 1) + 1 * 2 <= 10",
+			want:  []parser.ConditionsIR{{Left: "a", Op: "<=", Right: "5"}},
+		},
+		{
+			name:  "negative coefficient flips the operator",
+			query: "SELECT a FROM t WHERE 10 - a < 3",
+			want:  []parser.ConditionsIR{{Left: "a", Op: ">", Right: "7"}},
+		},
+		{
+			name:  "division",
+			query: "SELECT a FROM t WHERE a / 4.0 >= 2.5 AND NOT a - a + b * 2 = 3",
+			want: []parser.ConditionsIR{
+				{Left: "a", Op: ">=", Right: "10"},
+				{Left: "b", Op: "=", Right: "1.5", Negated: true},
+			},
+		},
+		{
+			name:  "division of ints truncates",
+			query: "SELECT a FROM t WHERE a / 4 >= 2.5 AND 0 > (1 - b) / 3 AND c / -2 = 3",
+			want: []parser.ConditionsIR{
+				{Left: "a", Op: ">=", Right: "12"},
+				{Left: "b", Op: ">=", Right: "4"},
+				{Left: "c", Op: "BETWEEN", Values: []parser.RightIR{"-7", "-6"}},
+			},
+		},
+		{
+			name:  "division of ints within a sum",
+			query: "SELECT a FROM t WHERE a / 2 + 1 > 5",
+			want: []parser.ConditionsIR{{
+				Left: "a / 2 + 1", Op: ">", Right: "5",
+				Unsupported: "division of ints in a / 2 truncates, which is only supported on its own on one side of a comparison",
+			}},
+		},
+		{
+			name:  "product of columns",
+			query: "SELECT a FROM t WHERE price * qty >= 100",
+			want: []parser.ConditionsIR{{
+				Left: "price * qty", Op: ">=", Right: "100",
+				Unsupported: "product of columns in price * qty is not linear",
+			}},
+		},
+		{
+			name:  "more than one column",
+			query: "SELECT a FROM t WHERE a + b > c",
+			want: []parser.ConditionsIR{{
+				Left: "a + b", Op: ">", Right: "c",
+				Unsupported: "comparison over more than one column (a, b, c)",
+			}},
+		},
+		{
+			name:  "modulo of a column",
+			query: "SELECT a FROM t WHERE a % 2 = 0",
+			want: []parser.ConditionsIR{{
+				Left: "a % 2", Op: "=", Right: "0",
+				Unsupported: "% is only supported between non zero constants",
+			}},
+		},
+		{
+			name:  "modulo of constants",
+			query: "SELECT a FROM t WHERE a > 5 % 0.5 AND b = 7 % 2.5 AND c < -7 % 2",
+			want: []parser.ConditionsIR{
+				{Left: "a", Op: ">", Right: "0"},
+				{Left: "b", Op: "=", Right: "2"},
+				{Left: "c", Op: "<", Right: "-1"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			q, err := parser.Parser.ParseString("", tt.query)
+			r.NoError(err)
+			r.Equal(tt.want, q.GetConditions())
+		})
+	}
+}
+
+func TestParse_ReversedParsing(t *testing.T) {
+	query := "SELECT a FROM t WHERE 10 < a AND '2024-01-01' <= ts AND 5 = b AND -1 <> c AND 1 = 1"
+	want := []parser.ConditionsIR{
+		{Left: "a", Op: ">", Right: "10"},
+		{Left: "ts", Op: ">=", Right: "'2024-01-01'"},
+		{Left: "b", Op: "=", Right: "5"},
+		{Left: "c", Op: "<>", Right: "-1"},
+		{Left: "1", Op: "=", Right: "1"},
+	}
+	r := require.New(t)
+	q, err := parser.Parser.ParseString("", query)
+	r.NoError(err)
+	r.Equal(want, q.GetConditions())
+}
+
+func TestParse_AliasParsing(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		wantFrom  parser.FromClause
+		wantJoins []string
+	}{
+		{
+			name:     "implicit alias",
+			query:    "SELECT o.amount FROM orders o WHERE o.amount > 5",
+			wantFrom: parser.FromClause{Table: &parser.QIdent{Parts: []string{"orders"}}, Alias: ptr("o")},
+		},
+		{
+			name:      "explicit alias and joined alias",
+			query:     "select o.amount from sales.orders as o left join customers c on o.cid = c.id where c.age > 5",
+			wantFrom:  parser.FromClause{Table: &parser.QIdent{Parts: []string{"sales", "orders"}}, Alias: ptr("o")},
+			wantJoins: []string{"c"},
+		},
+		{
+			name:     "no alias",
+			query:    "SELECT amount FROM orders WHERE amount > 5",
+			wantFrom: parser.FromClause{Table: &parser.QIdent{Parts: []string{"orders"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			q, err := parser.Parser.ParseString("", tt.query)
+			r.NoError(err)
+			r.Equal(tt.wantFrom, *q.From)
+			var gotJoins []string
+			for _, j := range q.Joins {
+				gotJoins = append(gotJoins, *j.Alias)
+			}
+			r.Equal(tt.wantJoins, gotJoins)
+		})
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func TestParse_QuotedIdentParsing(t *testing.T) {
+	query := `
+		SELECT "Order Date" /* the order date,
+		   on two lines */
+		FROM t
+		WHERE "Order Date" > '2024-01-01'
+			AND ` + "`my col`" + ` = 1
+			AND [T-SQL col] = 2
+			AND "say ""hi""" = 'x'
+			AND o."Amount" < 3 /**/
+	`
+	want := []parser.ConditionsIR{
+		{Left: "Order Date", Op: ">", Right: "'2024-01-01'"},
+		{Left: "my col", Op: "=", Right: "1"},
+		{Left: "T-SQL col", Op: "=", Right: "2"},
+		{Left: `say "hi"`, Op: "=", Right: "'x'"},
+		{Left: "o.Amount", Op: "<", Right: "3"},
+	}
+	r := require.New(t)
+	q, err := parser.Parser.ParseString("", query)
+	r.NoError(err)
+	r.Equal(want, q.GetConditions())
+}
+
+func TestParse_WithParsing(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		wantRecursive bool
+		wantCTEs      []string
+		wantColumns   [][]string
+	}{
+		{
+			name:        "single CTE",
+			query:       "WITH big AS (SELECT amount FROM orders WHERE amount > 5) SELECT amount FROM big",
+			wantCTEs:    []string{"big"},
+			wantColumns: [][]string{nil},
+		},
+		{
+			name: "chained CTEs with a column list",
+			query: `with big (total) as (select amount as total from orders where amount > 5),
+				small as (select * from big where total < 10)
+				select total from small`,
+			wantCTEs:    []string{"big", "small"},
+			wantColumns: [][]string{{"total"}, nil},
+		},
+		{
+			name:          "recursive CTE",
+			query:         "WITH RECURSIVE n AS (SELECT id FROM nums WHERE id = 1) SELECT id FROM n",
+			wantRecursive: true,
+			wantCTEs:      []string{"n"},
+			wantColumns:   [][]string{nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			q, err := parser.Parser.ParseString("", tt.query)
+			r.NoError(err)
+			r.NotNil(q.With)
+			r.Equal(tt.wantRecursive, q.With.Recursive)
+			var gotCTEs []string
+			var gotColumns [][]string
+			for _, c := range q.With.CTEs {
+				gotCTEs = append(gotCTEs, c.Name)
+				gotColumns = append(gotColumns, c.Columns)
+				r.NotNil(c.Query.Where)
+			}
+			r.Equal(tt.wantCTEs, gotCTEs)
+			r.Equal(tt.wantColumns, gotColumns)
+		})
+	}
+}
+
+func TestParse_DerivedTableParsing(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		wantFrom  string
+		wantJoins []string
+	}{
+		{
+			name:     "subquery in FROM",
+			query:    "SELECT s.total FROM (SELECT amount AS total FROM orders WHERE amount > 5) AS s WHERE s.total < 10",
+			wantFrom: "s",
+		},
+		{
+			name: "subquery in JOIN",
+			query: `select o.amount from orders o
+				join (select id from customers where age > 5) c on o.cid = c.id`,
+			wantJoins: []string{"c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			q, err := parser.Parser.ParseString("", tt.query)
+			r.NoError(err)
+			if tt.wantFrom != "" {
+				r.Nil(q.From.Table)
+				r.NotNil(q.From.Sub)
+				r.Equal(tt.wantFrom, *q.From.Alias)
+			}
+			var gotJoins []string
+			for _, j := range q.GetJoins() {
+				gotJoins = append(gotJoins, j.Table)
+			}
+			r.Equal(tt.wantJoins, gotJoins)
+		})
+	}
+}
+
+func TestParse_SubqueryPredicateParsing(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		wantOp   parser.OpIR
+		wantLeft parser.LeftIR
+		negated  bool
+	}{
+		{
+			name:   "exists",
+			query:  "SELECT id FROM c WHERE EXISTS (SELECT 1 FROM o WHERE o.cid = c.id)",
+			wantOp: "EXISTS",
+		},
+		{
+			name:    "not exists",
+			query:   "SELECT id FROM c WHERE NOT EXISTS (SELECT 1 FROM o WHERE o.cid = c.id)",
+			wantOp:  "EXISTS",
+			negated: true,
+		},
+		{
+			name:     "in subquery",
+			query:    "SELECT id FROM c WHERE id IN (SELECT cid FROM o WHERE o.amount > 5)",
+			wantOp:   "IN",
+			wantLeft: "id",
+		},
+		{
+			name:     "not in subquery",
+			query:    "SELECT id FROM c WHERE id NOT IN (SELECT cid FROM o)",
+			wantOp:   "IN",
+			wantLeft: "id",
+			negated:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			q, err := parser.Parser.ParseString("", tt.query)
+			r.NoError(err)
+			got := q.GetConditions()
+			r.Len(got, 1)
+			r.Equal(tt.wantOp, got[0].Op)
+			r.Equal(tt.wantLeft, got[0].Left)
+			r.Equal(tt.negated, got[0].Negated)
+			r.NotNil(got[0].Subquery)
+			r.Nil(got[0].Values)
+		})
+	}
+}
+
+func TestParse_SetOpParsing(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		wantArms     int
+		wantBranches []parser.Branch
+	}{
+		{
+			name:         "single select",
+			query:        "SELECT a FROM t WHERE a > 1",
+			wantArms:     1,
+			wantBranches: []parser.Branch{{Include: []int{0}}},
+		},
+		{
+			name:         "union all",
+			query:        "SELECT a FROM t WHERE a > 1 UNION ALL SELECT a FROM u WHERE a < 1",
+			wantArms:     2,
+			wantBranches: []parser.Branch{{Include: []int{0}}, {Include: []int{1}}},
+		},
+		{
+			name:         "intersect binds tighter than union",
+			query:        "select a from t union select a from u intersect select a from v",
+			wantArms:     3,
+			wantBranches: []parser.Branch{{Include: []int{0}}, {Include: []int{1, 2}}},
+		},
+		{
+			name:     "except applies to everything before it",
+			query:    "SELECT a FROM t UNION DISTINCT SELECT a FROM u EXCEPT SELECT a FROM v",
+			wantArms: 3,
+			wantBranches: []parser.Branch{
+				{Include: []int{0}, Exclude: []int{2}},
+				{Include: []int{1}, Exclude: []int{2}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			q, err := parser.Parser.ParseString("", tt.query)
+			r.NoError(err)
+			r.Len(q.Arms(), tt.wantArms)
+			r.Equal(tt.wantBranches, q.Branches())
+		})
+	}
+}
+
+func TestParse_GroupByParsing(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		wantGroupBy    []parser.ColumnIR
+		wantConditions []parser.ConditionsIR
+	}{
+		{
+			name:        "group by columns with having",
+			query:       "SELECT s.region, SUM(s.amount) FROM sales s GROUP BY s.region, day HAVING COUNT(*) >= 3 AND SUM(s.amount) > 1000",
+			wantGroupBy: []parser.ColumnIR{{Table: "s", Name: "region"}, {Name: "day"}},
+			wantConditions: []parser.ConditionsIR{
+				{Left: "*", Op: ">=", Right: "3", Aggregate: "COUNT"},
+				{Left: "s.amount", Op: ">", Right: "1000", Aggregate: "SUM"},
+			},
+		},
+		{
+			name:        "group by position",
+			query:       "select region, count(id) from sales where amount > 5 group by 1 having 2 < count(id)",
+			wantGroupBy: []parser.ColumnIR{{Name: "region"}},
+			wantConditions: []parser.ConditionsIR{
+				{Left: "amount", Op: ">", Right: "5"},
+				{Left: "id", Op: ">", Right: "2", Aggregate: "COUNT"},
+			},
+		},
+		{
+			name:        "distinct aggregate",
+			query:       "SELECT region FROM sales GROUP BY region HAVING COUNT(DISTINCT id) > 1",
+			wantGroupBy: []parser.ColumnIR{{Name: "region"}},
+			wantConditions: []parser.ConditionsIR{
+				{Op: ">", Right: "1", Aggregate: "COUNT", Unsupported: "COUNT(DISTINCT ...) is not supported"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			q, err := parser.Parser.ParseString("", tt.query)
+			r.NoError(err)
+			groupBy, err := q.GetGroupBy()
+			r.NoError(err)
+			r.Equal(tt.wantGroupBy, groupBy)
+			r.Equal(tt.wantConditions, q.GetConditions())
+		})
+	}
+}
+
+func TestParse_OrderByParsing(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		wantOrderBy []parser.OrderIR
+		wantMinRows int
+	}{
+		{
+			name:        "order by columns and positions",
+			query:       "SELECT a, b FROM t ORDER BY t.a DESC, 2 ASC, a + 1",
+			wantOrderBy: []parser.OrderIR{{Column: &parser.ColumnIR{Table: "t", Name: "a"}, Desc: true}, {Position: 2}},
+		},
+		{
+			name:        "quoted name holding a dot",
+			query:       `SELECT "t.a" FROM t ORDER BY "t.a"`,
+			wantOrderBy: []parser.OrderIR{{Column: &parser.ColumnIR{Name: "t.a"}}},
+		},
+		{
+			name:        "limit and offset",
+			query:       "select a from t where a > 1 order by a limit 10 offset 5",
+			wantOrderBy: []parser.OrderIR{{Column: &parser.ColumnIR{Name: "a"}}},
+			wantMinRows: 15,
+		},
+		{
+			name:        "offset alone",
+			query:       "SELECT a FROM t OFFSET 3",
+			wantOrderBy: []parser.OrderIR{},
+			wantMinRows: 4,
+		},
+		{
+			name:        "compound query",
+			query:       "SELECT a FROM t UNION ALL SELECT a FROM u ORDER BY 1 LIMIT 2",
+			wantOrderBy: []parser.OrderIR{{Position: 1}},
+			wantMinRows: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			q, err := parser.Parser.ParseString("", tt.query)
+			r.NoError(err)
+			r.Equal(tt.wantOrderBy, q.GetOrderBy())
+			r.Equal(tt.wantMinRows, q.MinRows())
+		})
+	}
+}
+
+func TestParse_CaseParsing(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected []parser.ConditionsIR
+		// wantAlternatives are the Alternatives of the condition, written
+		// out, which are left out of expected
+		wantAlternatives []string
+	}{
+		{
+			name:     "searched case",
+			query:    "SELECT id FROM t WHERE CASE WHEN tier = 'gold' THEN credit_limit ELSE credit_limit * 2 END > 100",
+			expected: []parser.ConditionsIR{{Left: "CASE", Op: "CASE"}},
+			wantAlternatives: []string{
+				"(tier = 'gold' AND credit_limit > 100)",
+				"(NOT tier = 'gold' AND credit_limit > 50)",
+			},
+		},
+		{
+			name:     "simple case on the right",
+			query:    "SELECT id FROM t WHERE 2 >= CASE tier WHEN 'gold' THEN 1 WHEN 'silver' THEN 2 ELSE 3 END",
+			expected: []parser.ConditionsIR{{Left: "CASE", Op: "CASE"}},
+			wantAlternatives: []string{
+				"tier = 'gold'",
+				"(NOT tier = 'gold' AND tier = 'silver')",
+			},
+		},
+		{
+			name:             "negated case without else",
+			query:            "SELECT id FROM t WHERE NOT CASE WHEN a > 1 THEN b END = 2",
+			expected:         []parser.ConditionsIR{{Left: "CASE", Op: "CASE"}},
+			wantAlternatives: []string{"(a > 1 AND NOT b = 2)"},
+		},
+		{
+			name:     "earlier WHEN with an OR",
+			query:    "SELECT id FROM t WHERE CASE WHEN a > 1 OR b > 2 THEN 1 WHEN a = 0 AND c = 1 THEN 2 ELSE 3 END >= 2",
+			expected: []parser.ConditionsIR{{Left: "CASE", Op: "CASE"}},
+			wantAlternatives: []string{
+				"(NOT a > 1 AND NOT b > 2 AND a = 0 AND c = 1)",
+				"(NOT a > 1 AND NOT b > 2 AND (NOT a = 0 OR NOT c = 1))",
+			},
+		},
+		{
+			name:  "no branch passes",
+			query: "SELECT id FROM t WHERE CASE WHEN a > 1 THEN 1 ELSE 2 END > 5",
+			expected: []parser.ConditionsIR{{
+				Left: "CASE", Op: "CASE", Unsupported: "no branch of the CASE passes the comparison",
+			}},
+		},
+		{
+			name:  "case outside a comparison",
+			query: "SELECT CASE WHEN a > 1 THEN 1 END AS c FROM t WHERE CASE WHEN a > 1 THEN 1 END IN (1)",
+			expected: []parser.ConditionsIR{{
+				Left: "CASE", Op: "IN", Values: []parser.RightIR{"1"},
+				Unsupported: "CASE is only supported in comparisons, not in IN",
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			q, err := parser.Parser.ParseString("", tt.query)
+			r.NoError(err)
+			got := q.GetConditions()
+			var alternatives []string
+			for i := range got {
+				for _, alt := range got[i].Alternatives {
+					alternatives = append(alternatives, alt.String())
+				}
+				got[i].Alternatives = nil
+			}
+			r.Equal(tt.expected, got)
+			r.Equal(tt.wantAlternatives, alternatives)
+		})
+	}
+}
+
+func TestParse_CastParsing(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected []parser.ConditionsIR
+	}{
+		{
+			name:     "cast to date",
+			query:    "SELECT id FROM t WHERE CAST(ts AS DATE) = '2024-01-01'",
+			expected: []parser.ConditionsIR{{Left: "ts", Op: "=", Right: "'2024-01-01'", Cast: "date"}},
+		},
+		{
+			name:     "double colon on the right",
+			query:    "SELECT id FROM t WHERE '2024-01-01' <= t.ts::Date",
+			expected: []parser.ConditionsIR{{Left: "t.ts", Op: ">=", Right: "'2024-01-01'", Cast: "date"}},
+		},
+		{
+			name:     "try cast with parameters and a cast literal",
+			query:    "SELECT id FROM t WHERE TRY_CAST(amount AS DECIMAL(10, 2)) > CAST('5' AS INT)",
+			expected: []parser.ConditionsIR{{Left: "amount", Op: ">", Right: "'5'", Cast: "decimal"}},
+		},
+		{
+			name:  "cast in list predicates",
+			query: "SELECT id FROM t WHERE ts::date BETWEEN '2024-01-01'::date AND '2024-01-31' AND NOT CAST(flag AS BOOLEAN)",
+			expected: []parser.ConditionsIR{
+				{Left: "ts", Op: "BETWEEN", Values: []parser.RightIR{"'2024-01-01'", "'2024-01-31'"}, Cast: "date"},
+				{Left: "flag", Op: "bool", Right: "true", Negated: true, Cast: "boolean"},
+			},
+		},
+		{
+			name:     "cast over arithmetic",
+			query:    "SELECT id FROM t WHERE CAST(a + 1 AS BIGINT) > 5",
+			expected: []parser.ConditionsIR{{Left: "a", Op: ">", Right: "4"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			q, err := parser.Parser.ParseString("", tt.query)
+			r.NoError(err)
+			r.Equal(tt.expected, q.GetConditions())
+		})
+	}
+}
+
+func TestParse_FuncParsing(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected []parser.ConditionsIR
+	}{
+		{
+			name:  "function of a column",
+			query: "SELECT id FROM t WHERE date(t.ts) = '2024-01-01'",
+			expected: []parser.ConditionsIR{{
+				Left: "DATE(t.ts)", Op: "=", Right: "'2024-01-01'",
+				Func: &parser.FuncIR{Name: "DATE", Args: []parser.ArgIR{{Column: &parser.ColumnIR{Table: "t", Name: "ts"}}}},
+			}},
+		},
+		{
+			name:  "function with a literal on the left",
+			query: "SELECT id FROM t WHERE 5 < coalesce(a, 0)",
+			expected: []parser.ConditionsIR{{
+				Left: "COALESCE(a, 0)", Op: ">", Right: "5",
+				Func: &parser.FuncIR{Name: "COALESCE", Args: []parser.ArgIR{{Column: &parser.ColumnIR{Name: "a"}}, {Literal: "0"}}},
+			}},
+		},
+		{
+			name:  "nested functions and expressions",
+			query: "SELECT id FROM t WHERE upper(trim(name, ' ')) LIKE 'A%' AND round(a * 2) IS NULL",
+			expected: []parser.ConditionsIR{
+				{
+					Left: "UPPER(TRIM(name, ' '))", Op: "LIKE", Right: "'A%'",
+					Func: &parser.FuncIR{Name: "UPPER", Args: []parser.ArgIR{{Func: &parser.FuncIR{
+						Name: "TRIM", Args: []parser.ArgIR{{Column: &parser.ColumnIR{Name: "name"}}, {Literal: "' '"}},
+					}}}},
+				},
+				{
+					Left: "ROUND(a * 2)", Op: "IS NULL",
+					Func: &parser.FuncIR{Name: "ROUND", Args: []parser.ArgIR{{Expr: "a * 2"}}},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			q, err := parser.Parser.ParseString("", tt.query)
+			r.NoError(err)
+			r.Equal(tt.expected, q.GetConditions())
+		})
+	}
+}
+
+func TestParse_TreeParsing(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantTree   string
+		wantPushed string
+	}{
+		{
+			name:       "AND binds tighter than OR",
+			query:      "SELECT a FROM t WHERE a > 5 OR a = 10 AND b = 5",
+			wantTree:   "(a > 5 OR (a = 10 AND b = 5))",
+			wantPushed: "(a > 5 OR (a = 10 AND b = 5))",
+		},
+		{
+			name:       "NOT over an OR",
+			query:      "SELECT a FROM t WHERE NOT (a > 5 OR b NOT IN (1, 2)) AND c",
+			wantTree:   "(NOT (a > 5 OR NOT b IN (1, 2)) AND c)",
+			wantPushed: "(NOT a > 5 AND b IN (1, 2) AND c)",
+		},
+		{
+			name:       "NOT over a NOT",
+			query:      "SELECT a FROM t WHERE NOT NOT (a IS NOT NULL OR NOT EXISTS (SELECT 1 FROM u))",
+			wantTree:   "NOT NOT (NOT a IS NULL OR NOT EXISTS (...))",
+			wantPushed: "(NOT a IS NULL OR NOT EXISTS (...))",
+		},
+		{
+			name:       "NOT over an AND of a CASE",
+			query:      "SELECT a FROM t WHERE NOT (b = 1 AND CASE WHEN c > 1 THEN d END = 2) AND SUM(e) > 3 HAVING COUNT(*) >= 2",
+			wantTree:   "(NOT (b = 1 AND CASE ...) AND SUM(e) > 3 AND COUNT(*) >= 2)",
+			wantPushed: "((NOT b = 1 OR CASE ...) AND SUM(e) > 3 AND COUNT(*) >= 2)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			q, err := parser.Parser.ParseString("", tt.query)
+			r.NoError(err)
+			tree := q.GetPredicate()
+			r.Equal(tt.wantTree, tree.String())
+			r.Equal(tt.wantPushed, parser.PushNot(tree).String())
+		})
+	}
+}
+
+func TestParse_TypedOperands(t *testing.T) {
+	query := `SELECT a FROM t WHERE t.id = 10 AND name IN ('x', 'it''s') AND ts >= '2024-01-01'
+		AND price < 9.5 AND flag = TRUE AND coalesce(a, 0) > 1 AND t.id = u.t_id
+		AND "a.b" = 1 AND u."a.b" = "a.b" AND big = 9007199254740993`
+	q, err := parser.Parser.ParseString("", query)
+	r := require.New(t)
+	r.NoError(err)
+	and, ok := q.GetPredicate().(*parser.AndIR)
+	r.True(ok)
+
+	type operands struct {
+		Column   *parser.ColumnIR
+		Other    *parser.ColumnIR
+		Literals []parser.LiteralIR
+	}
+	want := []operands{
+		{&parser.ColumnIR{Table: "t", Name: "id"}, nil, []parser.LiteralIR{{Kind: parser.IntLiteral, Int: 10, Float: 10}}},
+		{&parser.ColumnIR{Name: "name"}, nil, []parser.LiteralIR{
+			{Kind: parser.StringLiteral, Str: "x"}, {Kind: parser.StringLiteral, Str: "it's"},
+		}},
+		{&parser.ColumnIR{Name: "ts"}, nil, []parser.LiteralIR{{
+			Kind: parser.TimestampLiteral, Str: "2024-01-01", Time: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		}}},
+		{&parser.ColumnIR{Name: "price"}, nil, []parser.LiteralIR{{Kind: parser.FloatLiteral, Float: 9.5}}},
+		{&parser.ColumnIR{Name: "flag"}, nil, []parser.LiteralIR{{Kind: parser.BoolLiteral, Bool: true}}},
+		{nil, nil, []parser.LiteralIR{{Kind: parser.IntLiteral, Int: 1, Float: 1}}},
+		{&parser.ColumnIR{Table: "t", Name: "id"}, &parser.ColumnIR{Table: "u", Name: "t_id"}, nil},
+		{&parser.ColumnIR{Name: "a.b"}, nil, []parser.LiteralIR{{Kind: parser.IntLiteral, Int: 1, Float: 1}}},
+		{&parser.ColumnIR{Table: "u", Name: "a.b"}, &parser.ColumnIR{Name: "a.b"}, nil},
+		{&parser.ColumnIR{Name: "big"}, nil, []parser.LiteralIR{{Kind: parser.IntLiteral, Int: 9007199254740993, Float: 9007199254740992}}},
+	}
+	got := make([]operands, 0, len(and.Terms))
+	for _, term := range and.Terms {
+		p, ok := term.(*parser.PredIR)
+		r.True(ok)
+		got = append(got, operands{p.Column, p.Other, p.Literals})
+	}
+	r.Equal(want, got)
+}
+
+func TestParse_JoinParsing(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		schemas parser.Schemas
+		want    []parser.JoinIR
+		wantErr string
+	}{
+		{
+			name:  "schema-qualified table without ON",
+			query: "SELECT o.id FROM orders o CROSS JOIN sales.regions AS r",
+			want:  []parser.JoinIR{{Kind: "CROSS", Table: "sales.regions", Alias: "r"}},
+		},
+		{
+			name:  "USING after a single table",
+			query: "SELECT id FROM orders o LEFT JOIN sales.customers USING (cid, region)",
+			want: []parser.JoinIR{{
+				Kind: "LEFT", Table: "sales.customers", Using: []string{"cid", "region"},
+				Keys: []parser.JoinKeyIR{
+					{Left: "o.cid", Right: "sales.customers.cid"},
+					{Left: "o.region", Right: "sales.customers.region"},
+				},
+			}},
+		},
+		{
+			name: "USING resolved against the schemas",
+			query: `SELECT id FROM orders o JOIN customers c ON o.cid = c.id
+				JOIN regions USING (region)`,
+			schemas: parser.Schemas{
+				"orders":    {"id", "cid"},
+				"customers": {"id", "region"},
+				"regions":   {"region", "name"},
+			},
+			want: []parser.JoinIR{
+				{
+					Kind: "INNER", Table: "customers", Alias: "c",
+					Condition: []parser.ConditionsIR{{Left: "o.cid", Op: "=", Right: "c.id", RightColumn: true}},
+				},
+				{
+					Kind: "INNER", Table: "regions", Using: []string{"region"},
+					Keys: []parser.JoinKeyIR{{Left: "c.region", Right: "regions.region"}},
+				},
+			},
+		},
+		{
+			name:  "NATURAL join resolved against the schemas",
+			query: "SELECT id FROM shop.orders NATURAL LEFT JOIN shop.customers c",
+			schemas: parser.Schemas{
+				"orders":    {"id", "cid", "region"},
+				"customers": {"cid", "region", "name"},
+			},
+			want: []parser.JoinIR{{
+				Kind: "NATURAL LEFT", Table: "shop.customers", Alias: "c",
+				Keys: []parser.JoinKeyIR{
+					{Left: "shop.orders.cid", Right: "c.cid"},
+					{Left: "shop.orders.region", Right: "c.region"},
+				},
+			}},
+		},
+		{
+			name: "implicit join with its predicates in WHERE",
+			query: `SELECT a.x FROM a, sales.b, c AS z
+				WHERE a.id = b.a_id AND a.x > 5 AND z.b_id = b.id AND z.y = a.x`,
+			want: []parser.JoinIR{
+				{
+					Kind: "INNER", Table: "sales.b",
+					Condition: []parser.ConditionsIR{{Left: "a.id", Op: "=", Right: "b.a_id", RightColumn: true}},
+				},
+				{
+					Kind: "INNER", Table: "c", Alias: "z",
+					Condition: []parser.ConditionsIR{
+						{Left: "z.b_id", Op: "=", Right: "b.id", RightColumn: true},
+						{Left: "z.y", Op: "=", Right: "a.x", RightColumn: true},
+					},
+				},
+			},
+		},
+		{
+			name:  "implicit join with its predicate under an OR",
+			query: "SELECT a.x FROM a, b WHERE a.id = b.a_id OR a.x = 1",
+			want:  []parser.JoinIR{{Kind: "INNER", Table: "b"}},
+		},
+		{
+			name: "implicit join before an explicit one",
+			query: `SELECT a.x FROM a, b JOIN c ON c.id = b.c_id
+				WHERE b.a_id = a.id AND NOT b.x = a.x AND a.x > 5`,
+			want: []parser.JoinIR{
+				{
+					Kind: "INNER", Table: "b",
+					Condition: []parser.ConditionsIR{{Left: "b.a_id", Op: "=", Right: "a.id", RightColumn: true}},
+				},
+				{
+					Kind: "INNER", Table: "c",
+					Condition: []parser.ConditionsIR{{Left: "c.id", Op: "=", Right: "b.c_id", RightColumn: true}},
+				},
+			},
+		},
+		{
+			name:    "USING a column no table before has",
+			query:   "SELECT id FROM orders o JOIN customers c USING (region)",
+			schemas: parser.Schemas{"orders": {"id"}, "customers": {"region"}},
+			wantErr: "USING (region): no table before customers has the column",
+		},
+		{
+			name:    "NATURAL join of a table without a schema",
+			query:   "SELECT id FROM orders NATURAL JOIN customers",
+			schemas: parser.Schemas{"orders": {"id"}},
+			wantErr: "NATURAL JOIN customers: the columns of customers are not known",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			q, err := parser.Parser.ParseString("", tt.query)
+			r.NoError(err)
+			if tt.schemas == nil {
+				r.Equal(tt.want, q.GetJoins())
+				return
+			}
+			joins, err := q.ResolveJoins(tt.schemas)
+			if tt.wantErr != "" {
+				r.EqualError(err, tt.wantErr)
+				return
+			}
+			r.NoError(err)
+			r.Equal(tt.want, joins)
+		})
+	}
+}
+
+func TestParse_DialectParsing(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect string
+		query   string
+		want    []parser.ConditionsIR
+		funcs   []string
+		wantErr string
+	}{
+		{
+			name:    "postgres quoting and ILIKE",
+			dialect: "PostgreSQL",
+			query:   `SELECT "Amount" FROM t WHERE "Name" ILIKE 'it''s%'`,
+			want:    []parser.ConditionsIR{{Left: "Name", Op: "ILIKE", Right: "'it''s%'"}},
+		},
+		{
+			name:    "postgres reads qualify as a column",
+			dialect: "postgres",
+			query:   "SELECT qualify FROM t WHERE qualify > 1",
+			want:    []parser.ConditionsIR{{Left: "qualify", Op: ">", Right: "1"}},
+		},
+		{
+			name:    "postgres has no QUALIFY",
+			dialect: "postgres",
+			query:   "SELECT a FROM t WHERE a > 1 QUALIFY ROW_NUMBER() OVER (ORDER BY a) = 1",
+			wantErr: `1:29: unexpected token "QUALIFY"`,
+		},
+		{
+			name:    "databricks quoting, QUALIFY and function names",
+			dialect: "databricks",
+			query: "SELECT `a` FROM t WHERE `b` = \"it's\" AND nvl(a, 0) > 1 " +
+				"QUALIFY ROW_NUMBER() OVER (ORDER BY a) = 1",
+			want: []parser.ConditionsIR{
+				{Left: "b", Op: "=", Right: "'it''s'"},
+				{Left: "COALESCE(a, 0)", Op: ">", Right: "1"},
+				{Left: "ROW_NUMBER() OVER (ORDER BY a)", Op: "=", Right: "1"},
+			},
+			funcs: []string{"", "COALESCE", "ROW_NUMBER"},
+		},
+		{
+			name:    "databricks renames nested function calls",
+			dialect: "databricks",
+			query:   "SELECT a FROM t WHERE nvl(ifnull(a, 0), 1) > 1",
+			want:    []parser.ConditionsIR{{Left: "COALESCE(COALESCE(a, 0), 1)", Op: ">", Right: "1"}},
+			funcs:   []string{"COALESCE"},
+		},
+		{
+			name:    "spark is databricks",
+			dialect: "spark",
+			query:   "SELECT a FROM t WHERE MEAN(a) > 1",
+			want:    []parser.ConditionsIR{{Left: "a", Op: ">", Right: "1", Aggregate: "AVG"}},
+			funcs:   []string{""},
+		},
+		{
+			name:    "bigquery SAFE_CAST and IFNULL",
+			dialect: "bigquery",
+			query:   `SELECT a FROM t WHERE SAFE_CAST(a AS INT64) > 1 AND IFNULL(b, "x") = 'y'`,
+			want: []parser.ConditionsIR{
+				{Left: "a", Op: ">", Right: "1", Cast: "int64"},
+				{Left: "COALESCE(b, 'x')", Op: "=", Right: "'y'"},
+			},
+			funcs: []string{"", "COALESCE"},
+		},
+		{
+			name:    "postgres has no TRY_CAST",
+			dialect: "postgres",
+			query:   "SELECT a FROM t WHERE try_cast(a AS INT) > 1",
+			wantErr: "1:23: TRY_CAST is not supported in the postgres dialect",
+		},
+		{
+			name:    "databricks has no SAFE_CAST",
+			dialect: "databricks",
+			query:   "SELECT a FROM t WHERE a > 1 AND SAFE_CAST(a AS INT) > 1",
+			wantErr: "1:33: SAFE_CAST is not supported in the databricks dialect",
+		},
+		{
+			name:    "left and right name functions outside of a join",
+			dialect: "ansi",
+			query:   "SELECT left(s, 3) AS end FROM t left JOIN u ON t.a = u.a WHERE right(s, 1) = 'x' LIMIT 1",
+			want:    []parser.ConditionsIR{{Left: "RIGHT(s, 1)", Op: "=", Right: "'x'"}},
+			funcs:   []string{"RIGHT"},
+		},
+		{
+			name:    "ansi has no backtick quoting",
+			dialect: "ansi",
+			query:   "SELECT `a` FROM t",
+			wantErr: "1:8: lexer: invalid input text \"`a` FROM t\"",
+		},
+		{
+			name:    "unknown dialect",
+			dialect: "mysql",
+			query:   "SELECT a FROM t",
+			wantErr: `unknown dialect "mysql", expected one of ansi, bigquery, databricks, duckdb, generic, postgres, postgresql, snowflake, spark`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			d, err := parser.LookupDialect(tt.dialect)
+			var q *parser.Query
+			if err == nil {
+				q, err = d.ParseString("", tt.query)
+			}
+			if tt.wantErr != "" {
+				r.EqualError(err, tt.wantErr)
+				return
+			}
+			r.NoError(err)
+			conds := q.GetConditions()
+			for i, name := range tt.funcs {
+				if name == "" {
+					r.Nil(conds[i].Func)
+					continue
+				}
+				r.Equal(name, conds[i].Func.Name)
+				conds[i].Func = nil
+			}
+			r.Equal(tt.want, conds)
+		})
+	}
+}
+
+func TestParse_Diagnostics(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		schemas parser.Schemas
+		want    string
+	}{
+		{
+			name:  "unexpected token",
+			query: "SELECT a\nFROM t\nWHERE foo(b AS INT) = 1",
+			want:  "q.sql:3:13: unexpected token \"AS\" (expected \")\" (\"OVER\" \"(\" Window \")\")?)\n  WHERE foo(b AS INT) = 1\n              ^",
+		},
+		{
+			name:  "unexpected end of query",
+			query: "SELECT a FROM t WHERE a >",
+			want:  "q.sql:1:26: unexpected end of query (expected <number>)\n  SELECT a FROM t WHERE a >\n                           ^",
+		},
+		{
+			name:  "invalid input under a tab",
+			query: "SELECT a FROM t\n\tWHERE a ~ 1",
+			want:  "q.sql:2:10: lexer: invalid input text \"~ 1\"\n  \tWHERE a ~ 1\n  \t        ^",
+		},
+		{
+			name:    "misspelled USING column",
+			query:   "SELECT id FROM orders o\n  JOIN customers c USING (cusomer_id)",
+			schemas: parser.Schemas{"orders": {"customer_id"}, "customers": {"customer_id"}},
+			want: "q.sql:2:3: USING (cusomer_id): no table before customers has the column\n" +
+				"    JOIN customers c USING (cusomer_id)\n" +
+				"    ^\n" +
+				"  did you mean \"customer_id\"?",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			q, err := parser.Parser.ParseString("q.sql", tt.query)
+			if err == nil {
+				_, err = q.ResolveJoins(tt.schemas)
+			}
+			r.Error(err)
+			r.Equal(tt.want, parser.Diagnose(tt.query, err).Report())
+		})
+	}
+}
+
+func TestParse_Suggest(t *testing.T) {
+	r := require.New(t)
+	columns := []string{"amount", "Amounts", "customer_id", "id", "created_at"}
+	r.Equal([]string{"amount"}, parser.Suggest("amout", columns))
+	r.Equal([]string{"Amounts", "amount"}, parser.Suggest("amounts", columns))
+	r.Equal([]string{"customer_id"}, parser.Suggest("CUSTOMER_ID", columns))
+	r.Equal([]string{"id"}, parser.Suggest("ix", columns))
+	r.Nil(parser.Suggest("price", columns))
+}
+
+func TestParse_At(t *testing.T) {
+	r := require.New(t)
+	pos := lexer.Position{Filename: "q.sql", Line: 2, Column: 7}
+	shared := &parser.Diagnostic{Err: fmt.Errorf(`unknown column "amout"`), Suggestions: []string{"amount"}}
+
+	located := parser.At(pos, shared)
+	r.Equal(0, shared.Pos.Line)
+	r.Equal("q.sql:2:7: unknown column \"amout\"\n  did you mean \"amount\"?", located.(*parser.Diagnostic).Report())
+
+	wrapped := parser.At(pos, fmt.Errorf("column o.amout: %w", shared))
+	r.Equal(0, shared.Pos.Line)
+	r.EqualError(wrapped, `column o.amout: unknown column "amout"`)
+	r.Equal("q.sql:2:7: column o.amout: unknown column \"amout\"\n  did you mean \"amount\"?", wrapped.(*parser.Diagnostic).Report())
+
+	r.Equal("1:1: tables are only joined on =",
+		parser.At(lexer.Position{Line: 1, Column: 1}, fmt.Errorf("tables are only joined on =")).(*parser.Diagnostic).Report())
+}
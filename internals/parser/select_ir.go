@@ -0,0 +1,31 @@
+package parser
+
+// Column returns the column a select item reads when it is a plain
+// column reference, such as o.amount in SELECT o.amount AS total.
+func (s *SelectItem) Column() (ColumnIR, bool) {
+	if s == nil || s.Star {
+		return ColumnIR{}, false
+	}
+	p := s.Expr.arith().primary()
+	if p == nil || p.QIdent == nil {
+		return ColumnIR{}, false
+	}
+	return *p.QIdent.column(), true
+}
+
+// Name returns the name the select item is exposed under, which is its
+// alias or else the name of the column it reads. Stars and expressions
+// without an alias have no name.
+func (s *SelectItem) Name() string {
+	if s == nil {
+		return ""
+	}
+	if s.Alias != nil {
+		return *s.Alias
+	}
+	col, ok := s.Column()
+	if !ok {
+		return ""
+	}
+	return col.Name
+}
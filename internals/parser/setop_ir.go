@@ -0,0 +1,57 @@
+package parser
+
+import "strings"
+
+// Branch is a set of arms of a compound query that rows of the result
+// can come from. The rows of a branch come out of every arm in Include,
+// and out of none of the arms in Exclude. Arms are numbered in the order
+// returned by Query.Arms.
+type Branch struct {
+	Include []int
+	Exclude []int
+}
+
+// Arms returns the SELECT arms of a query in order, the first of which
+// is the query itself.
+func (q *Query) Arms() []*SelectCore {
+	arms := []*SelectCore{&q.SelectCore}
+	for _, op := range q.SetOps {
+		arms = append(arms, op.Arm)
+	}
+	return arms
+}
+
+// Branches resolves the set operations of a query into the branches the
+// rows of its result come from. INTERSECT binds tighter than UNION and
+// EXCEPT, which apply from left to right, so in a UNION b EXCEPT c both
+// the rows of a and of b have to stay out of c. A row stays out of an
+// intersection by staying out of its first arm, which is the one that
+// ends up in Exclude.
+func (q *Query) Branches() []Branch {
+	// Group the arms of each INTERSECT together
+	type term struct {
+		op   string
+		arms []int
+	}
+	terms := []term{{arms: []int{0}}}
+	for i, op := range q.SetOps {
+		if strings.EqualFold(op.Op, "INTERSECT") {
+			last := &terms[len(terms)-1]
+			last.arms = append(last.arms, i+1)
+			continue
+		}
+		terms = append(terms, term{op: op.Op, arms: []int{i + 1}})
+	}
+
+	var branches []Branch
+	for _, t := range terms {
+		if strings.EqualFold(t.op, "EXCEPT") {
+			for i := range branches {
+				branches[i].Exclude = append(branches[i].Exclude, t.arms[0])
+			}
+			continue
+		}
+		branches = append(branches, Branch{Include: t.arms})
+	}
+	return branches
+}
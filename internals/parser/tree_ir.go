@@ -0,0 +1,332 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+// BoolIR is a node of the boolean expression tree of a condition, which
+// is an AndIR, an OrIR, a NotIR or a PredIR. Unlike the flat list of
+// GetConditions, it keeps apart the terms of an OR, so a > 5 OR a = 10
+// AND b = 5 holds a > 5 on one side and a = 10 AND b = 5 on the other.
+type BoolIR interface {
+	String() string
+	pushNot(negated bool) BoolIR
+}
+
+// AndIR holds when all of its terms do.
+type AndIR struct {
+	Terms []BoolIR
+}
+
+// OrIR holds when any of its terms does.
+type OrIR struct {
+	Terms []BoolIR
+}
+
+// NotIR holds when its term does not.
+type NotIR struct {
+	Term BoolIR
+}
+
+// PredIR is a single predicate, the leaf of the tree. Column is the
+// column it constrains, if it is on one, and Other the column on the
+// other side of a comparison between two columns. Literals are its
+// literal operands, with their values parsed, which the constraints of
+// the solver are built from. An operand that is not a literal, such as
+// a column in an IN list, is left out. Op and Negated are those of its
+// condition, which holds the rest of the predicate, such as the
+// subquery of an EXISTS or the branches of a CASE. Pos is where the
+// predicate starts in the query.
+type PredIR struct {
+	Column   *ColumnIR
+	Other    *ColumnIR
+	Op       OpIR
+	Negated  bool
+	Literals []LiteralIR
+	Pos      lexer.Position
+
+	cond ConditionsIR
+	// cmp is the comparison the predicate comes from, under negated
+	// NOTs. It is read again when the predicate is negated, as a CASE
+	// does not negate as a whole.
+	cmp     *Cmp
+	negated bool
+}
+
+// Tree converts an Expr into its boolean expression tree. ORs and ANDs
+// in a row are gathered into a single node, and the terms of a
+// parenthesised expression become the terms of the tree around it.
+func (e *Expr) Tree() BoolIR {
+	terms := []BoolIR{e.Left.tree()}
+	for _, r := range e.Rest {
+		terms = append(terms, r.Right.tree())
+	}
+	return or(terms...)
+}
+
+func (a *And) tree() BoolIR {
+	terms := []BoolIR{a.Left.tree()}
+	for _, r := range a.Rest {
+		terms = append(terms, r.Right.tree())
+	}
+	return and(terms...)
+}
+
+func (n *Not) tree() BoolIR {
+	switch {
+	case n.Not != nil:
+		return &NotIR{Term: n.Not.tree()}
+	case n.Exists != nil:
+		return predOf(ConditionsIR{Op: OpIR("EXISTS"), Subquery: n.Exists}, n.Pos, nil)
+	default:
+		return n.Cmp.tree()
+	}
+}
+
+func (c *Cmp) tree() BoolIR {
+	if left := c.Left.primary(); c.Op == nil && left != nil && left.Paren != nil {
+		return left.Paren.Tree()
+	}
+	return newPred(c, false)
+}
+
+// and joins terms into an AndIR, taking in the terms of the ANDs among
+// them. A single term is returned as it is.
+func and(terms ...BoolIR) BoolIR {
+	if len(terms) == 1 {
+		return terms[0]
+	}
+	out := &AndIR{}
+	for _, t := range terms {
+		if a, ok := t.(*AndIR); ok {
+			out.Terms = append(out.Terms, a.Terms...)
+		} else {
+			out.Terms = append(out.Terms, t)
+		}
+	}
+	return out
+}
+
+// or joins terms into an OrIR, the same way and does.
+func or(terms ...BoolIR) BoolIR {
+	if len(terms) == 1 {
+		return terms[0]
+	}
+	out := &OrIR{}
+	for _, t := range terms {
+		if o, ok := t.(*OrIR); ok {
+			out.Terms = append(out.Terms, o.Terms...)
+		} else {
+			out.Terms = append(out.Terms, t)
+		}
+	}
+	return out
+}
+
+// newPred builds the leaf of a comparison.
+func newPred(cmp *Cmp, negated bool) *PredIR {
+	out := predOf(cmp.toIR(negated), cmp.Pos, cmp.idents())
+	out.cmp, out.negated = cmp, negated
+	return out
+}
+
+// predOf builds the leaf of a condition, along with its typed operands.
+// Its columns are taken from the identifiers the condition was written
+// with, rather than from its text, as the text of a quoted "a.b" reads
+// the same as column b of table a.
+func predOf(c ConditionsIR, pos lexer.Position, idents []*QIdent) *PredIR {
+	out := &PredIR{Op: c.Op, Negated: c.Negated, Pos: pos, cond: c}
+	switch {
+	case c.Unsupported != "", c.Func != nil, c.Left == "" || c.Left == "*":
+	case c.Op == "EXISTS" || c.Op == "CASE":
+	default:
+		out.Column, idents = columnOf(string(c.Left), idents)
+	}
+	if c.RightColumn {
+		out.Other, _ = columnOf(string(c.Right), idents)
+		return out
+	}
+	operands := c.Values
+	if operands == nil && c.Right != "" {
+		operands = []RightIR{c.Right}
+	}
+	for _, raw := range operands {
+		if lit, ok := literalIR(raw); ok {
+			out.Literals = append(out.Literals, lit)
+		}
+	}
+	return out
+}
+
+// columnOf returns the column of the first identifier written as the
+// given text, along with the identifiers that are left after it.
+func columnOf(text string, idents []*QIdent) (*ColumnIR, []*QIdent) {
+	for i, q := range idents {
+		if strings.Join(q.Parts, ".") == text {
+			return q.column(), append(idents[:i:i], idents[i+1:]...)
+		}
+	}
+	return nil, idents
+}
+
+// idents returns the identifiers of a comparison in the order they are
+// written, leaving out those of its subqueries.
+func (c *Cmp) idents() []*QIdent {
+	var out []*QIdent
+	w := walker{ident: func(q *QIdent) { out = append(out, q) }}
+	w.cmp(c)
+	return out
+}
+
+// Condition returns the condition of the predicate.
+func (p *PredIR) Condition() ConditionsIR {
+	return p.cond
+}
+
+// leaves returns the predicates of a tree, from left to right.
+func leaves(t BoolIR) []*PredIR {
+	switch t := t.(type) {
+	case *AndIR:
+		var out []*PredIR
+		for _, term := range t.Terms {
+			out = append(out, leaves(term)...)
+		}
+		return out
+	case *OrIR:
+		var out []*PredIR
+		for _, term := range t.Terms {
+			out = append(out, leaves(term)...)
+		}
+		return out
+	case *NotIR:
+		return leaves(t.Term)
+	case *PredIR:
+		return []*PredIR{t}
+	}
+	return nil
+}
+
+// PushNot returns a tree that holds exactly when the given one does,
+// with its NOTs pushed down into the predicates, so that it is made of
+// ANDs, ORs and predicates only. NOT (a OR b) becomes NOT a AND NOT b.
+func PushNot(t BoolIR) BoolIR {
+	if t == nil {
+		return nil
+	}
+	return t.pushNot(false)
+}
+
+func (a *AndIR) pushNot(negated bool) BoolIR {
+	terms := make([]BoolIR, 0, len(a.Terms))
+	for _, t := range a.Terms {
+		terms = append(terms, t.pushNot(negated))
+	}
+	if negated {
+		return or(terms...)
+	}
+	return and(terms...)
+}
+
+func (o *OrIR) pushNot(negated bool) BoolIR {
+	terms := make([]BoolIR, 0, len(o.Terms))
+	for _, t := range o.Terms {
+		terms = append(terms, t.pushNot(negated))
+	}
+	if negated {
+		return and(terms...)
+	}
+	return or(terms...)
+}
+
+func (n *NotIR) pushNot(negated bool) BoolIR {
+	return n.Term.pushNot(!negated)
+}
+
+func (p *PredIR) pushNot(negated bool) BoolIR {
+	if !negated {
+		return p
+	}
+	if p.cmp != nil {
+		return newPred(p.cmp, !p.negated)
+	}
+	out := *p
+	out.Negated = !out.Negated
+	out.cond.Negated = out.Negated
+	return &out
+}
+
+func (a *AndIR) String() string {
+	return joinTerms(a.Terms, " AND ")
+}
+
+func (o *OrIR) String() string {
+	return joinTerms(o.Terms, " OR ")
+}
+
+func (n *NotIR) String() string {
+	return "NOT " + n.Term.String()
+}
+
+func joinTerms(terms []BoolIR, sep string) string {
+	parts := make([]string, 0, len(terms))
+	for _, t := range terms {
+		parts = append(parts, t.String())
+	}
+	return "(" + strings.Join(parts, sep) + ")"
+}
+
+// String renders the predicate close to the way it would be written in
+// a query, with a negated predicate under a NOT.
+func (p *PredIR) String() string {
+	c := p.cond
+	left := string(c.Left)
+	if c.Aggregate != "" {
+		left = fmt.Sprintf("%s(%s)", c.Aggregate, c.Left)
+	}
+	var s string
+	switch {
+	case c.Op == "EXISTS":
+		s = "EXISTS (...)"
+	case c.Op == "CASE":
+		s = "CASE ..."
+	case c.Op == "bool":
+		s = left
+	case c.Op == "IS NULL":
+		s = left + " IS NULL"
+	case c.Subquery != nil:
+		s = fmt.Sprintf("%s %s (...)", left, c.Op)
+	case c.Op == "BETWEEN":
+		s = fmt.Sprintf("%s BETWEEN %s AND %s", left, c.Values[0], c.Values[1])
+	case c.Values != nil:
+		values := make([]string, 0, len(c.Values))
+		for _, v := range c.Values {
+			values = append(values, string(v))
+		}
+		s = fmt.Sprintf("%s %s (%s)", left, c.Op, strings.Join(values, ", "))
+	default:
+		s = fmt.Sprintf("%s %s %s", left, c.Op, c.Right)
+	}
+	if c.Negated {
+		return "NOT " + s
+	}
+	return s
+}
+
+// GetPredicate returns the WHERE, HAVING and QUALIFY clauses of a SELECT
+// as a single tree, which holds when all of them do, or nil for a SELECT
+// without any.
+func (q *SelectCore) GetPredicate() BoolIR {
+	var terms []BoolIR
+	for _, e := range []*Expr{q.Where, q.Having, q.Qualify} {
+		if e != nil {
+			terms = append(terms, e.Tree())
+		}
+	}
+	if len(terms) == 0 {
+		return nil
+	}
+	return and(terms...)
+}
@@ -0,0 +1,172 @@
+package parser
+
+// walker visits the nodes of a parsed query that a pass is after. It
+// calls ident on every identifier that may name a column, fn on every
+// function call and cast on every cast, and goes into subqueries only
+// when deep is set.
+type walker struct {
+	ident func(*QIdent)
+	fn    func(*Func)
+	cast  func(*Cast)
+	deep  bool
+}
+
+func (w *walker) query(q *Query) {
+	if q == nil || !w.deep {
+		return
+	}
+	if q.With != nil {
+		for _, cte := range q.With.CTEs {
+			w.query(cte.Query)
+		}
+	}
+	w.core(&q.SelectCore)
+	for _, op := range q.SetOps {
+		w.core(op.Arm)
+	}
+	for _, item := range q.OrderBy {
+		w.expr(item.Expr)
+	}
+}
+
+func (w *walker) core(c *SelectCore) {
+	if c == nil {
+		return
+	}
+	if c.Select != nil {
+		for _, item := range c.Select.Items {
+			w.expr(item.Expr)
+		}
+	}
+	if c.From != nil {
+		w.query(c.From.Sub)
+		for _, t := range c.From.Implicit {
+			w.query(t.Sub)
+		}
+	}
+	for _, j := range c.Joins {
+		w.query(j.Sub)
+		w.expr(j.On)
+	}
+	w.expr(c.Where)
+	for _, e := range c.GroupBy {
+		w.expr(e)
+	}
+	w.expr(c.Having)
+	w.expr(c.Qualify)
+}
+
+func (w *walker) expr(e *Expr) {
+	if e == nil {
+		return
+	}
+	w.and(e.Left)
+	for _, r := range e.Rest {
+		w.and(r.Right)
+	}
+}
+
+func (w *walker) and(a *And) {
+	if a == nil {
+		return
+	}
+	w.not(a.Left)
+	for _, r := range a.Rest {
+		w.not(r.Right)
+	}
+}
+
+func (w *walker) not(n *Not) {
+	if n == nil {
+		return
+	}
+	w.not(n.Not)
+	w.query(n.Exists)
+	w.cmp(n.Cmp)
+}
+
+func (w *walker) cmp(c *Cmp) {
+	if c == nil {
+		return
+	}
+	w.arith(c.Left)
+	w.arith(c.Right)
+	if c.In != nil {
+		w.query(c.In.Sub)
+		for _, v := range c.In.Values {
+			w.primary(v)
+		}
+	}
+	if c.Between != nil {
+		w.primary(c.Between.Low)
+		w.primary(c.Between.High)
+	}
+	if c.Like != nil {
+		w.primary(c.Like.Pattern)
+	}
+}
+
+func (w *walker) arith(a *Arith) {
+	if a == nil {
+		return
+	}
+	w.term(a.Left)
+	for _, r := range a.Rest {
+		w.term(r.Right)
+	}
+}
+
+func (w *walker) term(t *Term) {
+	if t == nil {
+		return
+	}
+	w.primary(t.Left)
+	for _, r := range t.Rest {
+		w.primary(r.Right)
+	}
+}
+
+func (w *walker) primary(p *Primary) {
+	if p == nil {
+		return
+	}
+	switch {
+	case p.Func != nil:
+		w.call(p.Func)
+	case p.QIdent != nil:
+		if w.ident != nil {
+			w.ident(p.QIdent)
+		}
+	case p.Paren != nil:
+		w.expr(p.Paren)
+	case p.Case != nil:
+		w.expr(p.Case.Operand)
+		for _, when := range p.Case.Whens {
+			w.expr(when.Cond)
+			w.expr(when.Then)
+		}
+		w.expr(p.Case.Else)
+	case p.Cast != nil:
+		if w.cast != nil {
+			w.cast(p.Cast)
+		}
+		w.expr(p.Cast.Expr)
+	}
+}
+
+func (w *walker) call(f *Func) {
+	if w.fn != nil {
+		w.fn(f)
+	}
+	for _, arg := range f.Args {
+		w.expr(arg)
+	}
+	if f.Over != nil {
+		for _, e := range f.Over.PartitionBy {
+			w.expr(e)
+		}
+		for _, item := range f.Over.OrderBy {
+			w.expr(item.Expr)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package parser
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
 
 // LeftIR represents the left side of a condition in the IR.
 type LeftIR string
@@ -17,16 +20,42 @@ type ConditionsIR struct {
 	Left  LeftIR
 	Op    OpIR
 	Right RightIR
+	// Values are the operands of IN, or the bounds of BETWEEN, which
+	// leave Right empty.
+	Values []RightIR
+	// Negated is set under an odd number of NOTs.
+	Negated bool
+	// Unsupported is why the condition cannot constrain a column, as
+	// for a comparison over a product of columns.
+	Unsupported string
+	// RightColumn is set when Right is a column, as in t2.k = t1.k.
+	RightColumn bool
+	// Subquery is the subquery of an EXISTS, or of an IN.
+	Subquery *Query
+	// Aggregate is the upper-cased aggregate around Left, as the SUM of
+	// SUM(amount) > 1000. Left is * for COUNT(*).
+	Aggregate string
+	// Alternatives are the ways a comparison on a CASE holds, each a
+	// tree without NOTs. Op is CASE.
+	Alternatives []BoolIR
+	// Cast is the lower-cased type Left is cast to.
+	Cast string
+	// Func is the function call on the left, which Left writes out.
+	Func *FuncIR
 }
 
 // primaryAtom converts a Primary expression into its string representation.
 // It handles qualified identifiers, numeric literals, string literals,
-// and function calls. If the Primary is nil or does not contain a
-// recognizable value, it returns an empty string.
+// and function calls with their arguments, and looks through the casts
+// around them. If the Primary is nil or does not contain a recognizable
+// value, it returns an empty string.
 func primaryAtom(p *Primary) string {
 	if p == nil {
 		return ""
 	}
+	if inner, _ := p.uncast(); inner != p {
+		return primaryAtom(inner)
+	}
 	if p.QIdent != nil && len(p.QIdent.Parts) > 0 {
 		return strings.Join(p.QIdent.Parts, ".")
 	}
@@ -37,74 +66,141 @@ func primaryAtom(p *Primary) string {
 		return *p.Str
 	}
 	if p.Func != nil {
-		return strings.Join(p.Func.Name.Parts, ".") + "()"
+		return p.Func.toIR().String()
 	}
 	return ""
 }
 
-// ToIR converts an Expr into a slice of ConditionsIR, representing the
-// intermediate form of the expression. It walks the expression tree,
-// extracting each condition and preserving logical operators.
-func (e *Expr) ToIR() []ConditionsIR {
-	conditions := make([]ConditionsIR, 0)
-	conditions = append(conditions, e.Left.ToIR()...)
+// isLiteral reports whether a Primary is a numeric, string or bool
+// literal.
+func isLiteral(p *Primary) bool {
+	return p != nil && (p.Num != nil || p.Str != nil || isBool(p))
+}
 
-	for _, orTerm := range e.Rest {
-		conditions = append(conditions, orTerm.Right.ToIR()...)
-	}
-	return conditions
+// ToIR converts an Expr into a slice of ConditionsIR, one for each
+// predicate of its tree, with the NOTs pushed down into them. The list
+// loses the difference between AND and OR, which the tree keeps.
+func (e *Expr) ToIR() []ConditionsIR {
+	return flatten(e.Tree())
 }
 
-// ToIR converts an And expression into a slice of ConditionsIR,
-// extracting each conjunctive condition. It handles the leftmost term
-// separately and then iterates over any additional terms.
+// ToIR converts an And expression into a slice of ConditionsIR, the same
+// way Expr.ToIR does.
 func (a *And) ToIR() []ConditionsIR {
+	return flatten(a.tree())
+}
+
+func flatten(t BoolIR) []ConditionsIR {
 	conditions := make([]ConditionsIR, 0)
+	for _, p := range leaves(PushNot(t)) {
+		conditions = append(conditions, p.cond)
+	}
+	return conditions
+}
 
-	if a.Left != nil {
-		if a.Left.Op != nil {
-			conditions = append(conditions, ConditionsIR{
-				Left:  LeftIR(primaryAtom(a.Left.Left)),
-				Op:    OpIR(*a.Left.Op),
-				Right: RightIR(primaryAtom(a.Left.Right)),
-			})
-		} else {
-			conditions = append(conditions, ConditionsIR{
-				Left:  LeftIR(primaryAtom(a.Left.Left)),
-				Op:    OpIR("bool"),
-				Right: RightIR("true"),
-			})
+// toIR converts a single comparison into a ConditionsIR. A bare operand
+// is read as a boolean column that has to be true, while a
+// parenthesised expression is left to the tree. NOT IN, NOT BETWEEN,
+// IS NOT NULL and NOT LIKE are kept as a negated IN, BETWEEN, IS NULL
+// and LIKE. A comparison with the literal on the left is turned
+// around, with its operator flipped.
+func (c *Cmp) toIR(negated bool) ConditionsIR {
+	left := c.Left.primary()
+	if c.Op != nil {
+		if cs, other, op, ok := c.caseSide(); ok {
+			return caseToIR(cs, op, other, negated)
+		}
+		right := c.Right.primary()
+		if left == nil || right == nil {
+			return arithToIR(c, negated)
+		}
+		left, cast := left.uncast()
+		right, rightCast := right.uncast()
+		op := *c.Op
+		// Keep the column on the left, so 10 < a becomes a > 10
+		if isLiteral(left) && !isLiteral(right) {
+			left, right = right, left
+			cast = rightCast
+			op = flipped[op]
+		}
+		if left.isCast() || right.isCast() {
+			// A cast over arithmetic, as in CAST(a + 1 AS INT) > 5
+			return arithToIR(c, negated)
+		}
+		if left.Func != nil && left.Func.aggregate() != "" {
+			return aggregateToIR(left.Func, op, right, negated)
+		}
+		return ConditionsIR{
+			Left:        LeftIR(primaryAtom(left)),
+			Op:          OpIR(op),
+			Right:       RightIR(primaryAtom(right)),
+			Negated:     negated,
+			RightColumn: left.QIdent != nil && right.QIdent != nil && !isBool(right),
+			Cast:        cast,
+			Func:        left.callIR(),
 		}
 	}
 
-	for _, andTerm := range a.Rest {
-		if andTerm.Right != nil {
-			if andTerm.Right.Op != nil {
-				conditions = append(conditions, ConditionsIR{
-					Left:  LeftIR(primaryAtom(andTerm.Right.Left)),
-					Op:    OpIR(*andTerm.Right.Op),
-					Right: RightIR(primaryAtom(andTerm.Right.Right)),
-				})
-			} else {
-				conditions = append(conditions, ConditionsIR{
-					Left:  LeftIR(primaryAtom(andTerm.Right.Left)),
-					Op:    OpIR("bool"),
-					Right: RightIR("true"),
-				})
-			}
+	out := ConditionsIR{
+		Left:    LeftIR(arithAtom(c.Left)),
+		Negated: negated,
+	}
+	if left != nil {
+		left, out.Cast = left.uncast()
+		out.Func = left.callIR()
+	}
+	switch {
+	case c.In != nil:
+		out.Op = OpIR("IN")
+		out.Negated = negated != c.In.Not
+		if c.In.Sub != nil {
+			out.Subquery = c.In.Sub
+			break
+		}
+		out.Values = make([]RightIR, 0, len(c.In.Values))
+		for _, v := range c.In.Values {
+			out.Values = append(out.Values, RightIR(primaryAtom(v)))
 		}
+	case c.Between != nil:
+		out.Op = OpIR("BETWEEN")
+		out.Values = []RightIR{RightIR(primaryAtom(c.Between.Low)), RightIR(primaryAtom(c.Between.High))}
+		out.Negated = negated != c.Between.Not
+	case c.Is != nil:
+		out.Op = OpIR("IS NULL")
+		out.Negated = negated != c.Is.Not
+	case c.Like != nil:
+		out.Op = OpIR(strings.ToUpper(c.Like.Op))
+		out.Right = RightIR(primaryAtom(c.Like.Pattern))
+		out.Negated = negated != c.Like.Not
+	default:
+		out.Op = OpIR("bool")
+		out.Right = RightIR("true")
 	}
-	return conditions
+	switch {
+	case left == nil:
+		out.Unsupported = fmt.Sprintf("arithmetic is only supported in comparisons, not in %s", out.Op)
+	case left.isCast():
+		out.Unsupported = fmt.Sprintf("CAST is only supported on columns and literals, not in %s", out.Op)
+	case left.Case != nil:
+		out.Left = "CASE"
+		out.Unsupported = fmt.Sprintf("CASE is only supported in comparisons, not in %s", out.Op)
+	}
+	return out
 }
 
-// GetConditions extracts all condition clauses from a Query, including
-// both the WHERE and QUALIFY clauses. It returns a flat slice of
-// ConditionsIR representing every condition in the query.
-func (q *Query) GetConditions() []ConditionsIR {
+// GetConditions extracts all condition clauses from a SELECT, including
+// the WHERE, HAVING and QUALIFY clauses. It returns a flat slice of
+// ConditionsIR representing every condition in the query, which loses
+// the difference between AND and OR, as GetPredicate keeps. On a
+// compound Query it covers the first arm.
+func (q *SelectCore) GetConditions() []ConditionsIR {
 	out := make([]ConditionsIR, 0)
 	if q.Where != nil {
 		out = append(out, q.Where.ToIR()...)
 	}
+	if q.Having != nil {
+		out = append(out, q.Having.ToIR()...)
+	}
 	if q.Qualify != nil {
 		out = append(out, q.Qualify.ToIR()...)
 	}
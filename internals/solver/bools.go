@@ -8,7 +8,8 @@ import (
 )
 
 type BoolDomain struct {
-	Condition bool
+	Nullability
+	Condition      bool
 	HasBeenChanged bool
 }
 
@@ -22,7 +23,7 @@ func (d *BoolDomain) GetTotalMax() any {
 
 func NewBoolDomain() *BoolDomain {
 	return &BoolDomain{
-		Condition: true,
+		Condition:      true,
 		HasBeenChanged: false,
 	}
 }
@@ -39,6 +40,11 @@ func (d BoolDomain) RandomValue(rng *rand.Rand) (any, error) {
 	return d.Condition, nil
 }
 
+// Size returns 1, as the domain holds a single value.
+func (d BoolDomain) Size() int {
+	return 1
+}
+
 type BoolTrue struct{ Value int }
 type BoolFalse struct{ Value int }
 
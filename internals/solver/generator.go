@@ -3,7 +3,9 @@ package solver
 import (
 	"fmt"
 	"math/rand"
+	"slices"
 	"sync"
+	"sync/atomic"
 
 	"github.com/phdah/sql-tdg/internals/table"
 	"github.com/phdah/sql-tdg/internals/types"
@@ -11,60 +13,122 @@ import (
 
 type Generator struct {
 	Columns []types.Column
+
+	// rand.Rand is not safe for concurrent use, and a value may take
+	// several draws, so the workers take turns drawing values
+	muRng sync.Mutex
+	muRow sync.Mutex
+}
+
+// NewDomain returns an unconstrained domain for a column of the given
+// type.
+func NewDomain(typ types.Type) (types.Domain, error) {
+	switch typ {
+	case types.IntType:
+		return NewIntDomain(), nil
+	case types.TimestampType:
+		return NewTimestampDomain(), nil
+	case types.BoolType:
+		return NewBoolDomain(), nil
+	case types.StringType:
+		return NewStringDomain(), nil
+	default:
+		return nil, fmt.Errorf("unsupported column type %v", typ)
+	}
 }
 
-func (g *Generator) generateColumn(domain types.Domain, col *types.Column, table *table.Table, rng *rand.Rand) {
-	var err error
-	for _, c := range col.Constraints {
-		err = c.Apply(domain)
+// maxDistinctAttempts bounds how many values are drawn for a distinct
+// column before giving up on finding one that is not taken.
+const maxDistinctAttempts = 100
+
+// generateValue applies the constraints to the domain, and draws a value
+// from it. A domain that is constrained to NULL gives a nil value. When
+// used is not nil, the value is one that is not in it yet.
+func (g *Generator) generateValue(domain types.Domain, constraints []types.Constraints, rng *rand.Rand, used map[any]bool) any {
+	for _, c := range constraints {
+		err := c.Apply(domain)
 		if err != nil {
 			panic(err)
 		}
 	}
-	value, err := domain.RandomValue(rng)
-	if err != nil {
-		panic(err)
+	if domain.IsNull() {
+		return nil
 	}
-	err = table.Append(col.Name, value)
-	if err != nil {
-		panic(err)
+	g.muRng.Lock()
+	defer g.muRng.Unlock()
+	for range maxDistinctAttempts {
+		value, err := domain.RandomValue(rng)
+		if err != nil {
+			panic(err)
+		}
+		if used == nil {
+			return value
+		}
+		if !used[value] {
+			used[value] = true
+			return value
+		}
 	}
+	panic(fmt.Sprintf("could not draw a distinct value after %d attempts", maxDistinctAttempts))
 }
 
+// generateRow draws a value for every column of a row, and appends them
+// all at once so that the columns stay aligned.
+func (g *Generator) generateRow(table *table.Table, row int, rng *rand.Rand, used map[string]map[any]bool) {
+	var branch map[string][]types.Constraints
+	if len(table.Branches) > 0 {
+		branch = table.Branches[row%len(table.Branches)]
+	}
+	var names []string
+	var values []any
+	for _, col := range table.Schema {
+		domain, err := NewDomain(col.Type)
+		if err != nil {
+			continue
+		}
+		constraints := append(slices.Clip(col.Constraints), branch[col.Name]...)
+		names = append(names, col.Name)
+		values = append(values, g.generateValue(domain, constraints, rng, used[col.Name]))
+	}
+
+	g.muRow.Lock()
+	defer g.muRow.Unlock()
+	for i, name := range names {
+		if err := table.Append(name, values[i]); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Generate fills the table with rows that meet the constraints of its
+// columns, spreading the rows evenly over the branches of the table.
 func (g *Generator) Generate(table *table.Table, seed int64) {
 	rng := rand.New(rand.NewSource(seed))
 	var wg sync.WaitGroup
 	workers := 4
-	if table.Dim.Rows%workers != 0 {
-		panic(
-			fmt.Sprintf("number of rows (%v) not dividable with number of workers (%v)",
-				table.Dim.Rows,
-				workers,
-			),
-		)
+
+	// The values taken so far by the distinct columns
+	used := make(map[string]map[any]bool)
+	for _, col := range table.Schema {
+		if col.Distinct {
+			used[col.Name] = make(map[any]bool)
+		}
 	}
-	valueSplit := table.Dim.Rows / workers
 
+	// Rows are numbered as they are generated, to spread them over the
+	// branches of the table, and the workers stop once every row is taken
+	var rows atomic.Int64
 	wg.Add(workers)
 	for range workers {
 		go func() {
 			defer wg.Done()
-			for range valueSplit {
-				for _, col := range table.Schema {
-					switch col.Type {
-					case types.IntType:
-						domain := NewIntDomain()
-						g.generateColumn(domain, &col, table, rng)
-					case types.TimestampType:
-						domain := NewTimestampDomain()
-						g.generateColumn(domain, &col, table, rng)
-					case types.BoolType:
-						domain := NewBoolDomain()
-						g.generateColumn(domain, &col, table, rng)
-					}
+			for {
+				row := int(rows.Add(1) - 1)
+				if row >= table.Dim.Rows {
+					return
 				}
+				g.generateRow(table, row, rng, used)
 			}
-
 		}()
 	}
 
@@ -1,6 +1,7 @@
 package solver_test
 
 import (
+	"math"
 	"testing"
 	"time"
 
@@ -49,7 +50,7 @@ func TestIntGenerator_Generate(t *testing.T) {
 				},
 			}, 12),
 			expected: map[string][]int{
-				"col_a": {28, 42, 48, 57, 58, 64, 71, 73, 76, 84, 90, 95},
+				"col_a": {9, 17, 27, 28, 28, 28, 45, 46, 53, 67, 69, 80},
 			},
 			expectedError: nil,
 		},
@@ -133,18 +134,18 @@ func TestTimestampGenerator_Generate(t *testing.T) {
 			}, 12),
 			expected: map[string][]time.Time{
 				"col_a": {
-					solver.FromInt(solver.ToTimestamp("2013-06-17T15:23:25Z")),
-					solver.FromInt(solver.ToTimestamp("2013-06-17T15:24:40Z")),
-					solver.FromInt(solver.ToTimestamp("2013-06-17T15:26:36Z")),
-					solver.FromInt(solver.ToTimestamp("2013-06-17T15:28:42Z")),
-					solver.FromInt(solver.ToTimestamp("2013-06-17T15:30:22Z")),
-					solver.FromInt(solver.ToTimestamp("2013-06-17T15:31:20Z")),
-					solver.FromInt(solver.ToTimestamp("2013-06-17T15:32:23Z")),
-					solver.FromInt(solver.ToTimestamp("2013-06-17T15:34:23Z")),
-					solver.FromInt(solver.ToTimestamp("2013-06-17T15:36:02Z")),
-					solver.FromInt(solver.ToTimestamp("2013-06-17T15:37:10Z")),
-					solver.FromInt(solver.ToTimestamp("2013-06-17T15:40:12Z")),
-					solver.FromInt(solver.ToTimestamp("2013-06-17T15:42:31Z")),
+					solver.FromInt(solver.ToTimestamp("2013-06-17T15:12:33Z")),
+					solver.FromInt(solver.ToTimestamp("2013-06-17T15:16:27Z")),
+					solver.FromInt(solver.ToTimestamp("2013-06-17T15:16:42Z")),
+					solver.FromInt(solver.ToTimestamp("2013-06-17T15:21:49Z")),
+					solver.FromInt(solver.ToTimestamp("2013-06-17T15:25:04Z")),
+					solver.FromInt(solver.ToTimestamp("2013-06-17T15:32:39Z")),
+					solver.FromInt(solver.ToTimestamp("2013-06-17T15:35:57Z")),
+					solver.FromInt(solver.ToTimestamp("2013-06-17T15:39:47Z")),
+					solver.FromInt(solver.ToTimestamp("2013-06-17T15:42:18Z")),
+					solver.FromInt(solver.ToTimestamp("2013-06-17T15:43:09Z")),
+					solver.FromInt(solver.ToTimestamp("2013-06-17T15:43:47Z")),
+					solver.FromInt(solver.ToTimestamp("2013-06-17T15:43:51Z")),
 				},
 			},
 			expectedError: nil,
@@ -277,3 +278,157 @@ func TestBoolGenerator_Generate(t *testing.T) {
 		})
 	}
 }
+
+func TestStringGenerator_Generate(t *testing.T) {
+	seed := int64(42)
+	tests := []struct {
+		name          string
+		table         *table.Table
+		expected      any
+		expectedError error
+	}{
+		{
+			name: "test with two columns",
+			table: table.NewTable([]types.Column{
+				{
+					Name: "col_a",
+					Type: types.StringType,
+					Constraints: []types.Constraints{
+						solver.StringEq{"a"},
+					},
+				},
+				{
+					Name: "col_b",
+					Type: types.StringType,
+					Constraints: []types.Constraints{
+						solver.StringIn{[]string{"b"}},
+					},
+				},
+			}, 4),
+			expected: map[string][]string{
+				"col_a": {"a", "a", "a", "a"},
+				"col_b": {"b", "b", "b", "b"},
+			},
+			expectedError: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			var g solver.Generator
+			g.Generate(tt.table, seed)
+			r.Equal(tt.expected, tt.table.Strings)
+		})
+	}
+}
+
+func TestBranchGenerator_Generate(t *testing.T) {
+	seed := int64(42)
+	branched := table.NewTable([]types.Column{
+		{
+			Name: "col_a",
+			Type: types.IntType,
+			Constraints: []types.Constraints{
+				solver.IntGt{0},
+			},
+		},
+		{
+			Name: "col_b",
+			Type: types.IntType,
+		},
+	}, 8)
+	branched.Branches = []map[string][]types.Constraints{
+		{"col_a": {solver.IntEq{1}}, "col_b": {solver.IntEq{10}}},
+		{"col_a": {solver.IntEq{2}}, "col_b": {solver.IntEq{20}}},
+	}
+
+	r := require.New(t)
+	var g solver.Generator
+	g.Generate(branched, seed)
+
+	// Every row comes from one of the branches, and they get half each
+	rows := map[[2]int]int{}
+	for i, a := range branched.Ints["col_a"] {
+		rows[[2]int{a, branched.Ints["col_b"][i]}]++
+	}
+	r.Equal(map[[2]int]int{{1, 10}: 4, {2, 20}: 4}, rows)
+	// The constraints of the columns still apply to every row
+	r.Len(branched.Schema[0].Constraints, 1)
+}
+
+func TestDistinctGenerator_Generate(t *testing.T) {
+	seed := int64(42)
+	distinct := table.NewTable([]types.Column{
+		{
+			Name: "col_a",
+			Type: types.IntType,
+			Constraints: []types.Constraints{
+				solver.IntBetween{Min: 1, Max: 10},
+			},
+			Distinct: true,
+		},
+	}, 10)
+
+	r := require.New(t)
+	var g solver.Generator
+	g.Generate(distinct, seed)
+	distinct.SortInts()
+	r.Equal(map[string][]int{"col_a": {1, 2, 3, 4, 5, 6, 7, 8, 9, 10}}, distinct.Ints)
+}
+
+func TestDomain_Size(t *testing.T) {
+	tests := []struct {
+		name        string
+		typ         types.Type
+		constraints []types.Constraints
+		want        int
+	}{
+		{
+			name:        "ints in two intervals",
+			typ:         types.IntType,
+			constraints: []types.Constraints{solver.IntBetween{Min: 1, Max: 10}, solver.IntNEq{5}},
+			want:        9,
+		},
+		{
+			name:        "a single timestamp",
+			typ:         types.TimestampType,
+			constraints: []types.Constraints{solver.IntEq{1700000000}},
+			want:        1,
+		},
+		{
+			name: "a bool",
+			typ:  types.BoolType,
+			want: 1,
+		},
+		{
+			name:        "strings left in a list",
+			typ:         types.StringType,
+			constraints: []types.Constraints{solver.StringIn{Values: []string{"a", "b", "c"}}, solver.StringNEq{Value: "b"}},
+			want:        2,
+		},
+		{
+			name:        "strings of a pattern without a %",
+			typ:         types.StringType,
+			constraints: []types.Constraints{solver.StringLike{Pattern: solver.NewPattern("a_", false)}},
+			want:        36,
+		},
+		{
+			name:        "strings of a pattern with a %",
+			typ:         types.StringType,
+			constraints: []types.Constraints{solver.StringLike{Pattern: solver.NewPattern("a%", false)}},
+			want:        math.MaxInt,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			domain, err := solver.NewDomain(tt.typ)
+			r.NoError(err)
+			for _, c := range tt.constraints {
+				r.NoError(c.Apply(domain))
+			}
+			r.Equal(tt.want, domain.Size())
+		})
+	}
+}
@@ -2,13 +2,16 @@ package solver
 
 import (
 	"fmt"
+	"math"
 	"math/rand"
+	"slices"
 
 	"github.com/phdah/sql-tdg/internals/types"
 	"github.com/phdah/sql-tdg/internals/utils"
 )
 
 type IntDomain struct {
+	Nullability
 	Intervals []types.Interval
 	TotalMin  int
 	TotalMax  int
@@ -36,24 +39,72 @@ func NewIntDomain() *IntDomain {
 func (d *IntDomain) SplitIntervals(splitValue any) error {
 	splitValueInt, ok := splitValue.(int)
 	if !ok {
-		return fmt.Errorf("expected int, got %T", splitValueInt)
+		return fmt.Errorf("expected int, got %T", splitValue)
 	}
+	err := d.ExcludeInterval(types.Interval{Min: splitValueInt, Max: splitValueInt})
+	if err != nil {
+		return fmt.Errorf("value not allowed: %v", splitValueInt)
+	}
+	return nil
+}
+
+// ExcludeInterval removes every value in the given interval from the
+// domain, splitting the intervals it cuts through in two.
+func (d *IntDomain) ExcludeInterval(excluded types.Interval) error {
 	var updated []types.Interval
 	for _, interval := range d.Intervals {
-		// If value is inside of interval, split it
-		if interval.Min < splitValueInt && splitValueInt < interval.Max {
+		// No overlap - keep it as is
+		if excluded.Max < interval.Min || interval.Max < excluded.Min {
+			updated = append(updated, interval)
+			continue
+		}
+		// Keep what is left on either side of the excluded interval
+		if interval.Min < excluded.Min {
 			updated = append(updated, types.Interval{
-				Min: interval.Min, Max: splitValueInt - 1,
+				Min: interval.Min, Max: excluded.Min - 1,
 			})
+		}
+		if excluded.Max < interval.Max {
 			updated = append(updated, types.Interval{
-				Min: splitValueInt + 1, Max: interval.Max,
+				Min: excluded.Max + 1, Max: interval.Max,
 			})
-		} else {
-			updated = append(updated, interval)
 		}
 	}
+	// If nothing is left - panic
+	if len(updated) <= 0 {
+		return fmt.Errorf("interval not allowed: %v", excluded)
+	}
 
 	d.Intervals = updated
+	d.TotalMin = updated[0].Min
+	d.TotalMax = updated[len(updated)-1].Max
+	return nil
+}
+
+// KeepValues narrows the domain down to the given values, dropping the
+// ones that are not inside any of the current intervals.
+func (d *IntDomain) KeepValues(values []int) error {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+
+	var updated []types.Interval
+	for _, v := range sorted {
+		for _, interval := range d.Intervals {
+			if interval.Min <= v && v <= interval.Max {
+				updated = append(updated, types.Interval{Min: v, Max: v})
+				break
+			}
+		}
+	}
+	// If no values overlap - panic
+	if len(updated) <= 0 {
+		return fmt.Errorf("values not allowed: %v", values)
+	}
+
+	d.Intervals = updated
+	d.TotalMin = updated[0].Min
+	d.TotalMax = updated[len(updated)-1].Max
 	return nil
 }
 
@@ -75,14 +126,6 @@ func (d *IntDomain) UpdateIntervals(newInterval types.Interval) error {
 			return fmt.Errorf("min value is larger than max value")
 		}
 
-		// Set new total min
-		if minv > d.TotalMin {
-			d.TotalMin = minv
-		}
-		// Set new total max
-		if maxv < d.TotalMax {
-			d.TotalMax = maxv
-		}
 		updated = append(updated, types.Interval{Min: minv, Max: maxv})
 	}
 	// If no intervals overlap - panic
@@ -91,6 +134,8 @@ func (d *IntDomain) UpdateIntervals(newInterval types.Interval) error {
 	}
 
 	d.Intervals = updated
+	d.TotalMin = updated[0].Min
+	d.TotalMax = updated[len(updated)-1].Max
 	return nil
 }
 
@@ -119,12 +164,38 @@ func (d IntDomain) RandomValue(rng *rand.Rand) (any, error) {
 	return nil, nil
 }
 
+// Size returns the number of values in the intervals, capped at
+// math.MaxInt.
+func (d IntDomain) Size() int {
+	total := 0
+	for _, interval := range d.Intervals {
+		count := interval.Max - interval.Min + 1
+		if count <= 0 || total > math.MaxInt-count {
+			return math.MaxInt
+		}
+		total += count
+	}
+	return total
+}
+
 type IntEq struct{ Value int }
 type IntNEq struct{ Value int }
 type IntLt struct{ Value int }
 type IntGt struct{ Value int }
 type IntLte struct{ Value int }
 type IntGte struct{ Value int }
+type IntIn struct{ Values []int }
+type IntNotIn struct{ Values []int }
+type IntBetween struct{ Min, Max int }
+type IntNotBetween struct{ Min, Max int }
+
+// intervalDomain is implemented by the domains built on IntDomain, that
+// is IntDomain and TimestampDomain, and lets constraints narrow them
+// down in ways types.Domain can not express.
+type intervalDomain interface {
+	KeepValues(values []int) error
+	ExcludeInterval(excluded types.Interval) error
+}
 
 func (c IntEq) Apply(domain types.Domain) error {
 	err := domain.UpdateIntervals(types.Interval{Min: c.Value, Max: c.Value})
@@ -163,3 +234,34 @@ func (c IntGte) Apply(domain types.Domain) error {
 	})
 	return err
 }
+
+func (c IntIn) Apply(domain types.Domain) error {
+	intervals, ok := domain.(intervalDomain)
+	if !ok {
+		return fmt.Errorf("expected IntDomain, got %T", domain)
+	}
+	return intervals.KeepValues(c.Values)
+}
+
+func (c IntNotIn) Apply(domain types.Domain) error {
+	for _, v := range c.Values {
+		err := domain.SplitIntervals(v)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c IntBetween) Apply(domain types.Domain) error {
+	err := domain.UpdateIntervals(types.Interval{Min: c.Min, Max: c.Max})
+	return err
+}
+
+func (c IntNotBetween) Apply(domain types.Domain) error {
+	intervals, ok := domain.(intervalDomain)
+	if !ok {
+		return fmt.Errorf("expected IntDomain, got %T", domain)
+	}
+	return intervals.ExcludeInterval(types.Interval{Min: c.Min, Max: c.Max})
+}
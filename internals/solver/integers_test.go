@@ -97,6 +97,62 @@ func TestInt_Single_Apply(t *testing.T) {
 			},
 			wantErr: nil,
 		},
+		{
+			name:   "only set one in list",
+			domain: solver.NewIntDomain(),
+			want: &solver.IntDomain{Intervals: []types.Interval{
+				{Min: 1, Max: 1},
+				{Min: 3, Max: 3},
+			},
+				TotalMin: 1,
+				TotalMax: 3},
+			conditions: []types.Constraints{
+				solver.IntIn{[]int{3, 1, 3}},
+			},
+			wantErr: nil,
+		},
+		{
+			name:   "only set one not in list",
+			domain: solver.NewIntDomain(),
+			want: &solver.IntDomain{Intervals: []types.Interval{
+				{Min: -1_000_000, Max: 0},
+				{Min: 2, Max: 2},
+				{Min: 4, Max: 1_000_000},
+			},
+				TotalMin: -1_000_000,
+				TotalMax: 1_000_000},
+			conditions: []types.Constraints{
+				solver.IntNotIn{[]int{1, 3}},
+			},
+			wantErr: nil,
+		},
+		{
+			name:   "only set one between",
+			domain: solver.NewIntDomain(),
+			want: &solver.IntDomain{Intervals: []types.Interval{
+				{Min: 10, Max: 20},
+			},
+				TotalMin: 10,
+				TotalMax: 20},
+			conditions: []types.Constraints{
+				solver.IntBetween{10, 20},
+			},
+			wantErr: nil,
+		},
+		{
+			name:   "only set one not between",
+			domain: solver.NewIntDomain(),
+			want: &solver.IntDomain{Intervals: []types.Interval{
+				{Min: -1_000_000, Max: 9},
+				{Min: 21, Max: 1_000_000},
+			},
+				TotalMin: -1_000_000,
+				TotalMax: 1_000_000},
+			conditions: []types.Constraints{
+				solver.IntNotBetween{10, 20},
+			},
+			wantErr: nil,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -157,6 +213,89 @@ func TestInt_Multi_Apply(t *testing.T) {
 			},
 			wantErr: fmt.Errorf("interval not allowed: {5 5}"),
 		},
+		{
+			name:   "in list inside range",
+			domain: solver.NewIntDomain(),
+			want: &solver.IntDomain{Intervals: []types.Interval{
+				{Min: 2, Max: 2},
+				{Min: 4, Max: 4},
+			},
+				TotalMin: 2,
+				TotalMax: 4},
+			conditions: []types.Constraints{
+				solver.IntGt{1},
+				solver.IntLte{5},
+				solver.IntNotIn{[]int{3, 5}},
+				solver.IntIn{[]int{1, 2, 3, 4, 5, 6}},
+			},
+			wantErr: nil,
+		},
+		{
+			name:   "in list narrowed by a range",
+			domain: solver.NewIntDomain(),
+			want: &solver.IntDomain{Intervals: []types.Interval{
+				{Min: 1, Max: 1},
+			},
+				TotalMin: 1,
+				TotalMax: 1},
+			conditions: []types.Constraints{
+				solver.IntIn{[]int{1, 4}},
+				solver.IntGt{0},
+				solver.IntLt{3},
+			},
+			wantErr: nil,
+		},
+		{
+			name:   "not in list removes the last value, panic",
+			domain: solver.NewIntDomain(),
+			want:   nil,
+			conditions: []types.Constraints{
+				solver.IntIn{[]int{1, 2}},
+				solver.IntNotIn{[]int{1, 2}},
+			},
+			wantErr: fmt.Errorf("value not allowed: 2"),
+		},
+		{
+			name:   "not between cuts through several intervals",
+			domain: solver.NewIntDomain(),
+			want: &solver.IntDomain{Intervals: []types.Interval{
+				{Min: 0, Max: 1},
+				{Min: 9, Max: 10},
+			},
+				TotalMin: 0,
+				TotalMax: 10},
+			conditions: []types.Constraints{
+				solver.IntBetween{0, 10},
+				solver.IntNEq{5},
+				solver.IntNotBetween{2, 8},
+			},
+			wantErr: nil,
+		},
+		{
+			name:   "not between cuts off both ends",
+			domain: solver.NewIntDomain(),
+			want: &solver.IntDomain{Intervals: []types.Interval{
+				{Min: 4, Max: 9},
+			},
+				TotalMin: 4,
+				TotalMax: 9},
+			conditions: []types.Constraints{
+				solver.IntBetween{0, 10},
+				solver.IntNotBetween{-5, 3},
+				solver.IntNEq{10},
+			},
+			wantErr: nil,
+		},
+		{
+			name:   "between outside of range, panic",
+			domain: solver.NewIntDomain(),
+			want:   nil,
+			conditions: []types.Constraints{
+				solver.IntLt{0},
+				solver.IntBetween{10, 20},
+			},
+			wantErr: fmt.Errorf("interval not allowed: {10 20}"),
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,41 @@
+package solver
+
+import (
+	"fmt"
+
+	"github.com/phdah/sql-tdg/internals/types"
+)
+
+// Nullability is embedded in every domain and keeps track of whether the
+// domain should produce NULL instead of a value.
+type Nullability struct {
+	Null           bool
+	NullHasChanged bool
+}
+
+func (n *Nullability) SetNull(null bool) error {
+	if n.NullHasChanged && n.Null != null {
+		if n.Null {
+			return fmt.Errorf("domain has already been set to IS NULL")
+		}
+		return fmt.Errorf("domain has already been set to IS NOT NULL")
+	}
+	n.Null = null
+	n.NullHasChanged = true
+	return nil
+}
+
+func (n *Nullability) IsNull() bool {
+	return n.Null
+}
+
+type IsNull struct{}
+type IsNotNull struct{}
+
+func (c IsNull) Apply(domain types.Domain) error {
+	return domain.SetNull(true)
+}
+
+func (c IsNotNull) Apply(domain types.Domain) error {
+	return domain.SetNull(false)
+}
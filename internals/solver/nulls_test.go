@@ -0,0 +1,93 @@
+package solver_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/phdah/sql-tdg/internals/solver"
+	"github.com/phdah/sql-tdg/internals/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNull_Apply(t *testing.T) {
+	tests := []struct {
+		name string // description of this test case
+		// Named input parameters for target function.
+		domain     types.Domain
+		want       types.Domain
+		wantErr    error
+		conditions []types.Constraints
+	}{
+		{
+			name:   "set int null",
+			domain: solver.NewIntDomain(),
+			want: &solver.IntDomain{
+				Nullability: solver.Nullability{Null: true, NullHasChanged: true},
+				Intervals:   []types.Interval{{Min: -1_000_000, Max: 1_000_000}},
+				TotalMin:    -1_000_000,
+				TotalMax:    1_000_000,
+			},
+			conditions: []types.Constraints{
+				solver.IsNull{},
+			},
+			wantErr: nil,
+		},
+		{
+			name:   "set bool not null",
+			domain: solver.NewBoolDomain(),
+			want: &solver.BoolDomain{
+				Nullability:    solver.Nullability{Null: false, NullHasChanged: true},
+				Condition:      false,
+				HasBeenChanged: true,
+			},
+			conditions: []types.Constraints{
+				solver.IsNotNull{},
+				solver.BoolFalse{},
+			},
+			wantErr: nil,
+		},
+		{
+			name:   "set timestamp null twice",
+			domain: solver.NewTimestampDomain(),
+			want: &solver.TimestampDomain{
+				IntDomain: solver.IntDomain{
+					Nullability: solver.Nullability{Null: true, NullHasChanged: true},
+					Intervals:   []types.Interval{{Min: 0, Max: 4102358400}},
+					TotalMin:    0,
+					TotalMax:    4102358400,
+				},
+			},
+			conditions: []types.Constraints{
+				solver.IsNull{},
+				solver.IsNull{},
+			},
+			wantErr: nil,
+		},
+		{
+			name:   "not allowed null condition error",
+			domain: solver.NewIntDomain(),
+			want:   nil,
+			conditions: []types.Constraints{
+				solver.IsNull{},
+				solver.IsNotNull{},
+			},
+			wantErr: fmt.Errorf("domain has already been set to IS NULL"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			var err error
+			for _, c := range tt.conditions {
+				err = c.Apply(tt.domain)
+				if tt.wantErr != nil && err != nil {
+					r.Error(err)
+					r.Contains(tt.wantErr.Error(), err.Error())
+					return
+				}
+				r.NoErrorf(err, fmt.Sprintf("Error was rasied: %v", err))
+			}
+			r.Equal(tt.want, tt.domain)
+		})
+	}
+}
@@ -0,0 +1,429 @@
+package solver
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/phdah/sql-tdg/internals/types"
+)
+
+// stringAlphabet holds the characters used to fill in the wildcards of a
+// pattern, and to make up unconstrained strings.
+const stringAlphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// maxStringAttempts bounds how many candidates RandomValue draws before
+// it gives up on finding a string that satisfies every constraint.
+const maxStringAttempts = 100
+
+// Pattern is a SQL LIKE pattern, where % matches any run of characters
+// and _ matches a single one. A backslash escapes the next character.
+// A Pattern built with NewPattern holds the pattern compiled already.
+type Pattern struct {
+	Pattern         string
+	CaseInsensitive bool
+
+	re *regexp.Regexp
+}
+
+// NewPattern builds a Pattern and compiles it, so that matching values
+// against it, as RandomValue does for every candidate, does not compile
+// it again.
+func NewPattern(pattern string, caseInsensitive bool) Pattern {
+	p := Pattern{Pattern: pattern, CaseInsensitive: caseInsensitive}
+	p.re = p.compile()
+	return p
+}
+
+// Matches reports whether value matches the pattern.
+func (p Pattern) Matches(value string) bool {
+	re := p.re
+	if re == nil {
+		re = p.compile()
+	}
+	return re.MatchString(value)
+}
+
+// compile turns the pattern into an anchored regular expression.
+func (p Pattern) compile() *regexp.Regexp {
+	var expr strings.Builder
+	expr.WriteString("^")
+	if p.CaseInsensitive {
+		expr.WriteString("(?i)")
+	}
+	escaped := false
+	for _, r := range p.Pattern {
+		switch {
+		case escaped:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			expr.WriteString("(?s:.*)")
+		case r == '_':
+			expr.WriteString("(?s:.)")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String())
+}
+
+// slot is a place in a string laid out from patterns: a character, a
+// wildcard that any one character fills, or one that any run of them
+// fills when run is set.
+type slot struct {
+	char rune // 0 for a wildcard
+	fold bool // char matches either case
+	run  bool
+}
+
+// slots splits the pattern into a slot per character and wildcard.
+func (p Pattern) slots() []slot {
+	var out []slot
+	escaped := false
+	for _, r := range p.Pattern {
+		switch {
+		case escaped:
+			out = append(out, slot{char: r, fold: p.CaseInsensitive})
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			out = append(out, slot{run: true})
+		case r == '_':
+			out = append(out, slot{})
+		default:
+			out = append(out, slot{char: r, fold: p.CaseInsensitive})
+		}
+	}
+	return out
+}
+
+// merge returns a slot that only takes characters both slots take, and
+// reports whether there is one.
+func (s slot) merge(other slot) (slot, bool) {
+	switch {
+	case s.char == 0:
+		return other, true
+	case other.char == 0:
+		return s, true
+	case s.char == other.char:
+		return slot{char: s.char, fold: s.fold && other.fold}, true
+	case (s.fold || other.fold) && strings.EqualFold(string(s.char), string(other.char)):
+		if s.fold {
+			return other, true
+		}
+		return s, true
+	}
+	return slot{}, false
+}
+
+// mergeAt merges the slots of src into those of dst from the given
+// offset on, and reports whether every one of them could be merged.
+func mergeAt(dst, src []slot, offset int) bool {
+	for i, s := range src {
+		merged, ok := dst[offset+i].merge(s)
+		if !ok {
+			return false
+		}
+		dst[offset+i] = merged
+	}
+	return true
+}
+
+// skeleton lays out the slots of a string that matches every pattern
+// once its wildcards are filled in. The patterns with a % share their
+// prefixes and suffixes, and have the parts between their %s one after
+// the other in the middle. A pattern without a % fixes the length of
+// the string, so only the prefixes and suffixes of the others are laid
+// out over it, and the parts in their middle are left to chance.
+func skeleton(patterns []Pattern) ([]slot, error) {
+	var fixed, middle []slot
+	var open []Pattern
+	var prefixes, suffixes [][]slot
+	for _, p := range patterns {
+		s := p.slots()
+		first, last := -1, -1
+		for i, slot := range s {
+			if slot.run {
+				if first < 0 {
+					first = i
+				}
+				last = i
+			}
+		}
+		if first < 0 {
+			if fixed == nil {
+				fixed = make([]slot, len(s))
+			}
+			if len(s) != len(fixed) || !mergeAt(fixed, s, 0) {
+				return nil, unmatched(p)
+			}
+			continue
+		}
+		open = append(open, p)
+		prefixes = append(prefixes, s[:first])
+		suffixes = append(suffixes, s[last+1:])
+		middle = append(middle, s[first:last]...)
+	}
+
+	// Without a pattern that fixes the length, the prefixes and suffixes
+	// get room of their own on either side of the middle
+	prefix, suffix := fixed, fixed
+	if fixed == nil {
+		for i := range open {
+			prefix = append(prefix, make([]slot, max(len(prefixes[i])-len(prefix), 0))...)
+			suffix = append(make([]slot, max(len(suffixes[i])-len(suffix), 0)), suffix...)
+		}
+	}
+	for i, p := range open {
+		pre, suf := prefixes[i], suffixes[i]
+		if len(pre) > len(prefix) || len(suf) > len(suffix) ||
+			!mergeAt(prefix, pre, 0) || !mergeAt(suffix, suf, len(suffix)-len(suf)) {
+			return nil, unmatched(p)
+		}
+	}
+	if fixed != nil {
+		return fixed, nil
+	}
+	out := append(prefix, middle...)
+	out = append(out, slot{run: true})
+	return append(out, suffix...), nil
+}
+
+// unmatched reports a pattern no string matches along with the others.
+func unmatched(p Pattern) error {
+	return fmt.Errorf("no string matches %q along with the other patterns", p.Pattern)
+}
+
+// fill builds a random string from slots, keeping their characters as
+// they are and filling in the wildcards from stringAlphabet.
+func fill(slots []slot, rng *rand.Rand) string {
+	var out strings.Builder
+	for _, s := range slots {
+		switch {
+		case s.run:
+			out.WriteString(randomString(rng, 1+rng.Intn(5)))
+		case s.char == 0:
+			out.WriteString(randomString(rng, 1))
+		default:
+			out.WriteRune(s.char)
+		}
+	}
+	return out.String()
+}
+
+func randomString(rng *rand.Rand, length int) string {
+	out := make([]byte, length)
+	for i := range out {
+		out[i] = stringAlphabet[rng.Intn(len(stringAlphabet))]
+	}
+	return string(out)
+}
+
+type StringDomain struct {
+	Nullability
+	Values         []string // Allowed values, nil allows any value
+	ExcludedValues []string
+	Patterns       []Pattern // Patterns the value has to match
+	Excluded       []Pattern // Patterns the value must not match
+}
+
+func (d *StringDomain) GetTotalMin() any {
+	return nil
+}
+
+func (d *StringDomain) GetTotalMax() any {
+	return nil
+}
+
+func NewStringDomain() *StringDomain {
+	return &StringDomain{}
+}
+
+func (d *StringDomain) SplitIntervals(splitValue any) error {
+	return nil
+}
+
+func (d *StringDomain) UpdateIntervals(newInterval types.Interval) error {
+	return nil
+}
+
+// accepts reports whether value satisfies every constraint on the domain.
+func (d StringDomain) accepts(value string) bool {
+	if d.Values != nil && !slices.Contains(d.Values, value) {
+		return false
+	}
+	if slices.Contains(d.ExcludedValues, value) {
+		return false
+	}
+	for _, p := range d.Patterns {
+		if !p.Matches(value) {
+			return false
+		}
+	}
+	for _, p := range d.Excluded {
+		if p.Matches(value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (d StringDomain) RandomValue(rng *rand.Rand) (any, error) {
+	// A fixed set of values, pick one of those that are left
+	if d.Values != nil {
+		var candidates []string
+		for _, v := range d.Values {
+			if d.accepts(v) {
+				candidates = append(candidates, v)
+			}
+		}
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("no values to generate")
+		}
+		return candidates[rng.Intn(len(candidates))], nil
+	}
+
+	// Otherwise build candidates that match every pattern, and check them
+	// against the rest of the constraints
+	slots, err := skeleton(d.Patterns)
+	if err != nil {
+		return nil, err
+	}
+	for range maxStringAttempts {
+		var candidate string
+		if len(d.Patterns) > 0 {
+			candidate = fill(slots, rng)
+		} else {
+			candidate = randomString(rng, 1+rng.Intn(8))
+		}
+		if d.accepts(candidate) {
+			return candidate, nil
+		}
+	}
+	return nil, fmt.Errorf("could not generate a string matching %v", d.Patterns)
+}
+
+// Size returns the number of values the domain can give, which is
+// math.MaxInt unless it is down to a fixed set of values or to patterns
+// that fix the length of the string.
+func (d StringDomain) Size() int {
+	if d.Values != nil {
+		n := 0
+		for _, v := range d.Values {
+			if d.accepts(v) {
+				n++
+			}
+		}
+		return n
+	}
+	if len(d.Patterns) == 0 {
+		return math.MaxInt
+	}
+	slots, err := skeleton(d.Patterns)
+	if err != nil {
+		return 0
+	}
+	n := 1
+	for _, s := range slots {
+		switch {
+		case s.run, s.char == 0 && n > math.MaxInt/len(stringAlphabet):
+			return math.MaxInt
+		case s.char == 0:
+			n *= len(stringAlphabet)
+		}
+	}
+	return n
+}
+
+type StringEq struct{ Value string }
+type StringNEq struct{ Value string }
+type StringIn struct{ Values []string }
+type StringNotIn struct{ Values []string }
+type StringLike struct{ Pattern Pattern }
+type StringNotLike struct{ Pattern Pattern }
+
+func asStringDomain(domain types.Domain) (*StringDomain, error) {
+	stringDomain, ok := domain.(*StringDomain)
+	if !ok {
+		return nil, fmt.Errorf("expected StringDomain, got %T", domain)
+	}
+	return stringDomain, nil
+}
+
+// keepValues narrows the allowed values down to the given ones.
+func (d *StringDomain) keepValues(values []string) error {
+	var updated []string
+	for _, v := range values {
+		if (d.Values == nil || slices.Contains(d.Values, v)) && !slices.Contains(updated, v) {
+			updated = append(updated, v)
+		}
+	}
+	if len(updated) <= 0 {
+		return fmt.Errorf("values not allowed: %v", values)
+	}
+	d.Values = updated
+	return nil
+}
+
+func (c StringEq) Apply(domain types.Domain) error {
+	stringDomain, err := asStringDomain(domain)
+	if err != nil {
+		return err
+	}
+	return stringDomain.keepValues([]string{c.Value})
+}
+
+func (c StringNEq) Apply(domain types.Domain) error {
+	stringDomain, err := asStringDomain(domain)
+	if err != nil {
+		return err
+	}
+	stringDomain.ExcludedValues = append(stringDomain.ExcludedValues, c.Value)
+	return nil
+}
+
+func (c StringIn) Apply(domain types.Domain) error {
+	stringDomain, err := asStringDomain(domain)
+	if err != nil {
+		return err
+	}
+	return stringDomain.keepValues(c.Values)
+}
+
+func (c StringNotIn) Apply(domain types.Domain) error {
+	stringDomain, err := asStringDomain(domain)
+	if err != nil {
+		return err
+	}
+	stringDomain.ExcludedValues = append(stringDomain.ExcludedValues, c.Values...)
+	return nil
+}
+
+func (c StringLike) Apply(domain types.Domain) error {
+	stringDomain, err := asStringDomain(domain)
+	if err != nil {
+		return err
+	}
+	stringDomain.Patterns = append(stringDomain.Patterns, c.Pattern)
+	if stringDomain.Size() == 0 {
+		return fmt.Errorf("no value matches %q along with the other constraints", c.Pattern.Pattern)
+	}
+	return nil
+}
+
+func (c StringNotLike) Apply(domain types.Domain) error {
+	stringDomain, err := asStringDomain(domain)
+	if err != nil {
+		return err
+	}
+	stringDomain.Excluded = append(stringDomain.Excluded, c.Pattern)
+	return nil
+}
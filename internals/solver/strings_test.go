@@ -0,0 +1,209 @@
+package solver_test
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/phdah/sql-tdg/internals/solver"
+	"github.com/phdah/sql-tdg/internals/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestString_Apply(t *testing.T) {
+	tests := []struct {
+		name string // description of this test case
+		// Named input parameters for target function.
+		domain     types.Domain
+		want       types.Domain
+		wantErr    error
+		conditions []types.Constraints
+	}{
+		{
+			name:   "set one equal",
+			domain: solver.NewStringDomain(),
+			want: &solver.StringDomain{
+				Values: []string{"a"},
+			},
+			conditions: []types.Constraints{
+				solver.StringEq{"a"},
+			},
+			wantErr: nil,
+		},
+		{
+			name:   "in list and not in list",
+			domain: solver.NewStringDomain(),
+			want: &solver.StringDomain{
+				Values:         []string{"SE", "NO"},
+				ExcludedValues: []string{"NO"},
+			},
+			conditions: []types.Constraints{
+				solver.StringIn{[]string{"SE", "NO", "SE"}},
+				solver.StringNotIn{[]string{"NO"}},
+			},
+			wantErr: nil,
+		},
+		{
+			name:   "like and not like",
+			domain: solver.NewStringDomain(),
+			want: &solver.StringDomain{
+				Patterns: []solver.Pattern{{Pattern: "%@corp.com"}},
+				Excluded: []solver.Pattern{{Pattern: "admin%", CaseInsensitive: true}},
+			},
+			conditions: []types.Constraints{
+				solver.StringLike{solver.Pattern{Pattern: "%@corp.com"}},
+				solver.StringNotLike{solver.Pattern{Pattern: "admin%", CaseInsensitive: true}},
+			},
+			wantErr: nil,
+		},
+		{
+			name:   "not allowed values, panic",
+			domain: solver.NewStringDomain(),
+			want:   nil,
+			conditions: []types.Constraints{
+				solver.StringEq{"a"},
+				solver.StringIn{[]string{"b", "c"}},
+			},
+			wantErr: fmt.Errorf("values not allowed: [b c]"),
+		},
+		{
+			name:   "like patterns with different prefixes, panic",
+			domain: solver.NewStringDomain(),
+			want:   nil,
+			conditions: []types.Constraints{
+				solver.StringLike{solver.Pattern{Pattern: "a%"}},
+				solver.StringLike{solver.Pattern{Pattern: "b%"}},
+			},
+			wantErr: errors.New(`no value matches "b%" along with the other constraints`),
+		},
+		{
+			name:   "like patterns of different lengths, panic",
+			domain: solver.NewStringDomain(),
+			want:   nil,
+			conditions: []types.Constraints{
+				solver.StringLike{solver.Pattern{Pattern: "a_"}},
+				solver.StringLike{solver.Pattern{Pattern: "a__"}},
+			},
+			wantErr: errors.New(`no value matches "a__" along with the other constraints`),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			var err error
+			for _, c := range tt.conditions {
+				err = c.Apply(tt.domain)
+				if tt.wantErr != nil && err != nil {
+					r.Error(err)
+					r.Contains(tt.wantErr.Error(), err.Error())
+					return
+				}
+				r.NoErrorf(err, fmt.Sprintf("Error was rasied: %v", err))
+			}
+			r.Equal(tt.want, tt.domain)
+		})
+	}
+}
+
+func TestPattern_Matches(t *testing.T) {
+	tests := []struct {
+		name    string // description of this test case
+		pattern solver.Pattern
+		value   string
+		want    bool
+	}{
+		{"suffix", solver.Pattern{Pattern: "%@corp.com"}, "x7a@corp.com", true},
+		{"suffix mismatch", solver.Pattern{Pattern: "%@corp.com"}, "x7a@corp.se", false},
+		{"single character", solver.Pattern{Pattern: "a_c"}, "abc", true},
+		{"single character too long", solver.Pattern{Pattern: "a_c"}, "abbc", false},
+		{"case sensitive", solver.Pattern{Pattern: "ABC"}, "abc", false},
+		{"case insensitive", solver.Pattern{Pattern: "ABC", CaseInsensitive: true}, "abc", true},
+		{"escaped wildcard", solver.Pattern{Pattern: `100\%`}, "100%", true},
+		{"escaped wildcard mismatch", solver.Pattern{Pattern: `100\%`}, "1000", false},
+		{"regexp characters", solver.Pattern{Pattern: "a.c"}, "abc", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			r.Equal(tt.want, tt.pattern.Matches(tt.value))
+			compiled := solver.NewPattern(tt.pattern.Pattern, tt.pattern.CaseInsensitive)
+			r.Equal(tt.want, compiled.Matches(tt.value))
+		})
+	}
+}
+
+func TestString_RandomValue(t *testing.T) {
+	tests := []struct {
+		name       string // description of this test case
+		conditions []types.Constraints
+		check      func(value string) bool
+	}{
+		{
+			name: "like pattern",
+			conditions: []types.Constraints{
+				solver.StringLike{solver.Pattern{Pattern: "%@corp.com"}},
+			},
+			check: solver.Pattern{Pattern: "_%@corp.com"}.Matches,
+		},
+		{
+			name: "like with excluded pattern",
+			conditions: []types.Constraints{
+				solver.StringLike{solver.Pattern{Pattern: "_"}},
+				solver.StringNotLike{solver.Pattern{Pattern: "a"}},
+			},
+			check: func(value string) bool { return len(value) == 1 && value != "a" },
+		},
+		{
+			name: "like patterns with a prefix and a suffix",
+			conditions: []types.Constraints{
+				solver.StringLike{solver.Pattern{Pattern: "a%"}},
+				solver.StringLike{solver.Pattern{Pattern: "%z"}},
+			},
+			check: func(value string) bool { return strings.HasPrefix(value, "a") && strings.HasSuffix(value, "z") },
+		},
+		{
+			name: "like patterns with parts between their wildcards",
+			conditions: []types.Constraints{
+				solver.StringLike{solver.Pattern{Pattern: "x%ab%"}},
+				solver.StringLike{solver.Pattern{Pattern: "%cd%y"}},
+				solver.StringLike{solver.Pattern{Pattern: "X_%", CaseInsensitive: true}},
+			},
+			check: func(value string) bool {
+				return solver.Pattern{Pattern: "x_%ab%cd%y"}.Matches(value) || solver.Pattern{Pattern: "x_%cd%ab%y"}.Matches(value)
+			},
+		},
+		{
+			name: "like patterns over a fixed length",
+			conditions: []types.Constraints{
+				solver.StringLike{solver.Pattern{Pattern: "%c"}},
+				solver.StringLike{solver.Pattern{Pattern: "a__"}},
+			},
+			check: solver.Pattern{Pattern: "a_c"}.Matches,
+		},
+		{
+			name: "in list with excluded value",
+			conditions: []types.Constraints{
+				solver.StringIn{[]string{"SE", "NO"}},
+				solver.StringNEq{"NO"},
+			},
+			check: func(value string) bool { return value == "SE" },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			rng := rand.New(rand.NewSource(42))
+			domain := solver.NewStringDomain()
+			for _, c := range tt.conditions {
+				r.NoError(c.Apply(domain))
+			}
+			for range 20 {
+				value, err := domain.RandomValue(rng)
+				r.NoError(err)
+				r.Truef(tt.check(value.(string)), "unexpected value %q", value)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package table
 
 import (
+	"fmt"
 	"sort"
 	"sync"
 	"time"
@@ -14,17 +15,34 @@ type Dim struct {
 }
 
 type Table struct {
+	// Name is the name the table is referred to by in queries. A table
+	// without a name stands for the table in the FROM clause.
+	Name   string
 	Schema []types.Column
 	Types  map[string]types.Type
 	Dim    Dim
 
+	// Branches split the rows of the table between sets of constraints,
+	// which apply on top of the constraints of the columns, so that every
+	// arm of a query such as a UNION gets rows of its own. Each set maps
+	// a column name onto its constraints.
+	Branches []map[string][]types.Constraints
+
 	Ints       map[string][]int
 	Timestamps map[string][]time.Time
 	Bools      map[string][]bool
+	Strings    map[string][]string
+
+	// Nulls holds, for every nullable column, whether the value at the
+	// same position in the typed map is NULL. The typed map keeps the
+	// zero value of its type in that position.
+	Nulls map[string][]bool
 
 	muInts       sync.Mutex
 	muTimestamps sync.Mutex
 	muBools      sync.Mutex
+	muStrings    sync.Mutex
+	muNulls      sync.Mutex
 }
 
 func getColTypes(schema []types.Column) map[string]types.Type {
@@ -35,6 +53,16 @@ func getColTypes(schema []types.Column) map[string]types.Type {
 	return types
 }
 
+func getNullable(schema []types.Column) map[string][]bool {
+	nulls := make(map[string][]bool)
+	for _, col := range schema {
+		if col.Nullable {
+			nulls[col.Name] = nil
+		}
+	}
+	return nulls
+}
+
 func NewTable(schema []types.Column, rows int) *Table {
 	return &Table{
 		Schema:     schema,
@@ -43,27 +71,65 @@ func NewTable(schema []types.Column, rows int) *Table {
 		Ints:       make(map[string][]int),
 		Timestamps: make(map[string][]time.Time),
 		Bools:      make(map[string][]bool),
+		Strings:    make(map[string][]string),
+		Nulls:      getNullable(schema),
 	}
 }
 
+// Append adds a value to the end of a column. A nil value is stored as
+// NULL, which is only allowed for nullable columns.
 func (t *Table) Append(col string, val any) error {
+	isNull := val == nil
+	if isNull && !t.isNullable(col) {
+		return fmt.Errorf("column %s is not nullable", col)
+	}
 	switch t.Types[col] {
 	case types.IntType:
 		t.muInts.Lock()
-		t.Ints[col] = append(t.Ints[col], val.(int))
+		v, _ := val.(int)
+		t.Ints[col] = append(t.Ints[col], v)
+		t.appendNull(col, isNull)
 		t.muInts.Unlock()
 	case types.TimestampType:
 		t.muTimestamps.Lock()
-		t.Timestamps[col] = append(t.Timestamps[col], val.(time.Time))
+		v, _ := val.(time.Time)
+		t.Timestamps[col] = append(t.Timestamps[col], v)
+		t.appendNull(col, isNull)
 		t.muTimestamps.Unlock()
 	case types.BoolType:
 		t.muBools.Lock()
-		t.Bools[col] = append(t.Bools[col], val.(bool))
+		v, _ := val.(bool)
+		t.Bools[col] = append(t.Bools[col], v)
+		t.appendNull(col, isNull)
 		t.muBools.Unlock()
+	case types.StringType:
+		t.muStrings.Lock()
+		v, _ := val.(string)
+		t.Strings[col] = append(t.Strings[col], v)
+		t.appendNull(col, isNull)
+		t.muStrings.Unlock()
 	}
 	return nil
 }
 
+func (t *Table) isNullable(col string) bool {
+	t.muNulls.Lock()
+	defer t.muNulls.Unlock()
+	_, ok := t.Nulls[col]
+	return ok
+}
+
+// appendNull records whether the value just appended to a nullable column
+// is NULL. It is called while the lock of the column type is held, so
+// the mask stays in the same order as the values.
+func (t *Table) appendNull(col string, isNull bool) {
+	t.muNulls.Lock()
+	defer t.muNulls.Unlock()
+	if _, ok := t.Nulls[col]; ok {
+		t.Nulls[col] = append(t.Nulls[col], isNull)
+	}
+}
+
 func (t *Table) GetInts(col string) ([]int, error) {
 	t.muInts.Lock()
 	defer t.muInts.Unlock()
@@ -82,12 +148,58 @@ func (t *Table) GetBools(col string) ([]bool, error) {
 	return t.Bools[col], nil
 }
 
+func (t *Table) GetStrings(col string) ([]string, error) {
+	t.muStrings.Lock()
+	defer t.muStrings.Unlock()
+	return t.Strings[col], nil
+}
+
+func (t *Table) GetNulls(col string) ([]bool, error) {
+	t.muNulls.Lock()
+	defer t.muNulls.Unlock()
+	return t.Nulls[col], nil
+}
+
+// sortColumn sorts the values of a column in place, keeping its NULL
+// mask, if it has one, aligned with the values and the NULLs first.
+func sortColumn[T any](values []T, nulls []bool, less func(a, b T) bool) {
+	if len(nulls) != len(values) {
+		sort.Slice(values, func(i int, j int) bool {
+			return less(values[i], values[j])
+		})
+		return
+	}
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i int, j int) bool {
+		a, b := order[i], order[j]
+		if nulls[a] || nulls[b] {
+			return nulls[a] && !nulls[b]
+		}
+		return less(values[a], values[b])
+	})
+	sortedValues := make([]T, len(values))
+	sortedNulls := make([]bool, len(nulls))
+	for i, k := range order {
+		sortedValues[i] = values[k]
+		sortedNulls[i] = nulls[k]
+	}
+	copy(values, sortedValues)
+	copy(nulls, sortedNulls)
+}
+
 func (t *Table) SortInts() {
 	t.muInts.Lock()
 	defer t.muInts.Unlock()
+	t.muNulls.Lock()
+	defer t.muNulls.Unlock()
 	for _, col := range t.Schema {
 		if col.Type == types.IntType {
-			sort.Ints(t.Ints[col.Name])
+			sortColumn(t.Ints[col.Name], t.Nulls[col.Name], func(a, b int) bool {
+				return a < b
+			})
 		}
 	}
 }
@@ -95,11 +207,26 @@ func (t *Table) SortInts() {
 func (t *Table) SortTimestamps() {
 	t.muTimestamps.Lock()
 	defer t.muTimestamps.Unlock()
+	t.muNulls.Lock()
+	defer t.muNulls.Unlock()
 	for _, col := range t.Schema {
 		if col.Type == types.TimestampType {
-			slice := t.Timestamps[col.Name]
-			sort.Slice(slice, func(i int, j int) bool {
-				return slice[i].Before(slice[j])
+			sortColumn(t.Timestamps[col.Name], t.Nulls[col.Name], func(a, b time.Time) bool {
+				return a.Before(b)
+			})
+		}
+	}
+}
+
+func (t *Table) SortStrings() {
+	t.muStrings.Lock()
+	defer t.muStrings.Unlock()
+	t.muNulls.Lock()
+	defer t.muNulls.Unlock()
+	for _, col := range t.Schema {
+		if col.Type == types.StringType {
+			sortColumn(t.Strings[col.Name], t.Nulls[col.Name], func(a, b string) bool {
+				return a < b
 			})
 		}
 	}
@@ -136,3 +136,66 @@ func TestTable_SortInts(t *testing.T) {
 		})
 	}
 }
+
+func TestTable_AppendNull(t *testing.T) {
+	tests := []struct {
+		name      string // description of this test case
+		columns   []types.Column
+		col       string
+		vals      []any
+		wantInts  []int
+		wantNulls []bool
+		wantErr   bool
+	}{
+		{
+			name: "nullable column",
+			columns: []types.Column{
+				{
+					Name:     "col_a",
+					Type:     types.IntType,
+					Nullable: true,
+				},
+			},
+			col:       "col_a",
+			vals:      []any{3, nil, 1},
+			wantInts:  []int{3, 0, 1},
+			wantNulls: []bool{false, true, false},
+		},
+		{
+			name: "not nullable column",
+			columns: []types.Column{
+				{
+					Name: "col_a",
+					Type: types.IntType,
+				},
+			},
+			col:     "col_a",
+			vals:    []any{nil},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			ta := table.NewTable(tt.columns, len(tt.vals))
+			for _, val := range tt.vals {
+				err := ta.Append(tt.col, val)
+				if tt.wantErr {
+					r.Error(err)
+					return
+				}
+				r.NoError(err)
+			}
+			ints, _ := ta.GetInts(tt.col)
+			nulls, _ := ta.GetNulls(tt.col)
+			r.Equal(tt.wantInts, ints)
+			r.Equal(tt.wantNulls, nulls)
+
+			ta.SortInts()
+			ints, _ = ta.GetInts(tt.col)
+			nulls, _ = ta.GetNulls(tt.col)
+			r.Equal([]int{0, 1, 3}, ints)
+			r.Equal([]bool{true, false, false}, nulls)
+		})
+	}
+}
@@ -14,7 +14,11 @@ const (
 type Column struct {
 	Name        string
 	Type        Type
+	Nullable    bool
 	Constraints []Constraints
+	// Distinct makes every row take a different value, as the ordering
+	// key of a query needs for its order to be deterministic
+	Distinct bool
 }
 
 type Constraints interface {
@@ -30,6 +34,9 @@ type Domain interface {
 	GetTotalMin() any                        // Get intervals max
 	GetTotalMax() any                        // Get intervals min
 	RandomValue(rng *rand.Rand) (any, error) // Generate random value
+	Size() int                               // Number of values left
 	UpdateIntervals(interval Interval) error // Add another interval
 	SplitIntervals(splitValue any) error     // Split intervals
+	SetNull(null bool) error                 // Generate NULL, or values
+	IsNull() bool                            // Whether to generate NULL
 }